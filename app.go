@@ -7,84 +7,216 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 	_ "modernc.org/sqlite"
 )
 
 type App struct {
-	ctx   context.Context
-	db    *sql.DB
-	paths AppPaths
+	ctx       context.Context
+	db        *dbHandle
+	history   undoHistory
+	downloads downloadRegistry
+	profiles  profileRegistry
+	jobs      backgroundJobs
 }
 
 type AppPaths struct {
-	BaseDir   string `json:"baseDir"`
-	DataDir   string `json:"dataDir"`
-	ImagesDir string `json:"imagesDir"`
-	DBPath    string `json:"dbPath"`
+	BaseDir       string `json:"baseDir"`
+	DataDir       string `json:"dataDir"`
+	ImagesDir     string `json:"imagesDir"`
+	UndoDir       string `json:"undoDir"`
+	QuarantineDir string `json:"quarantineDir"`
+	ScansDir      string `json:"scansDir"`
+	DBPath        string `json:"dbPath"`
 }
 
 func NewApp() *App {
-	return &App{}
+	return &App{db: newDBHandle(nil, AppPaths{})}
+}
+
+// paths returns the folders of the open collection, which change together
+// with the database.
+func (a *App) paths() AppPaths {
+	return a.db.currentPaths()
 }
 
 func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
 
-	paths, err := resolveAppPaths()
+	root, mode, err := resolveRootDir()
 	if err != nil {
 		runtime.LogFatal(ctx, fmt.Sprintf("failed to resolve app paths: %v", err))
 		return
 	}
-	a.paths = paths
+	a.profiles.root, a.profiles.mode = root, mode
 
-	if err := ensureDirs(paths); err != nil {
-		runtime.LogFatal(ctx, fmt.Sprintf("failed to prepare app folders: %v", err))
+	profiles, err := a.profiles.load()
+	if err != nil {
+		runtime.LogWarning(ctx, fmt.Sprintf("failed to load profiles, using the default profile: %v", err))
+		profiles.Active = defaultProfileID
+	}
+	if err := a.openCollection(appPathsFor(a.profiles.baseDir(profiles.Active))); err != nil {
+		runtime.LogFatal(ctx, err.Error())
 		return
 	}
+}
+
+func (a *App) shutdown(ctx context.Context) {
+	a.closeCollection(a.paths())
+	_ = a.db.Close()
+}
+
+// openCollection opens the database in paths and makes it the current
+// collection. The new database is migrated before the current one is closed,
+// so a collection that fails to open leaves the app as it was.
+func (a *App) openCollection(paths AppPaths) error {
+	db, err := a.prepareDatabase(paths)
+	if err != nil {
+		return err
+	}
 
+	// open cannot fail, so an error means the swap never started.
+	err = a.replaceCollection(func(AppPaths) (*sql.DB, AppPaths, error) {
+		return db, paths, nil
+	})
+	if err != nil {
+		db.Close()
+		return err
+	}
+	return nil
+}
+
+// replaceCollection closes the open collection and installs the one returned
+// by open, which gets the folders of the closed collection. The old
+// collection is torn down inside the swap, while no other call can run, so a
+// swap that cannot start leaves it untouched. The new collection is tidied
+// once it is in place.
+func (a *App) replaceCollection(open func(paths AppPaths) (*sql.DB, AppPaths, error)) error {
+	replaced := false
+	err := a.db.swap(func(old *sql.DB, paths AppPaths) (*sql.DB, AppPaths, error) {
+		replaced = true
+		a.closeCollection(paths)
+		if old != nil {
+			_ = old.Close()
+		}
+		return open(paths)
+	})
+	if replaced && a.db.isOpen() {
+		a.afterOpen()
+	}
+	return err
+}
+
+// prepareDatabase opens the database of a collection and brings its schema
+// and settings up to date without touching the database currently in use.
+func (a *App) prepareDatabase(paths AppPaths) (*sql.DB, error) {
+	if err := ensureDirs(paths); err != nil {
+		return nil, fmt.Errorf("failed to prepare app folders: %w", err)
+	}
 	db, err := openDatabase(paths.DBPath)
 	if err != nil {
-		runtime.LogFatal(ctx, fmt.Sprintf("failed to open database: %v", err))
-		return
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	next := &App{ctx: a.ctx, db: newDBHandle(db, paths)}
+	if err := next.runMigrations(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to run migrations: %w", err)
+	}
+	if err := next.upgradeSettings(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to upgrade settings: %w", err)
 	}
+	return db, nil
+}
 
-	a.db = db
+// closeCollection stops everything tied to the collection in paths before its
+// database is closed or replaced. Downloads, undo steps and scans of the
+// closed collection are of no use any more.
+func (a *App) closeCollection(paths AppPaths) {
+	a.jobs.stop()
+	a.CancelDownload(0)
+	a.clearUndoHistory(paths.UndoDir)
+	if _, err := cleanupScans(paths.ScansDir, 0); err != nil {
+		a.logWarning(fmt.Sprintf("failed to clean up scans: %v", err))
+	}
+}
 
-	if err := a.runMigrations(); err != nil {
-		runtime.LogFatal(ctx, fmt.Sprintf("failed to run migrations: %v", err))
-		return
+// afterOpen tidies up a collection that was just opened.
+func (a *App) afterOpen() {
+	// Held photos and staged scans left by a crash can no longer be used.
+	a.removeUnheldImages()
+	if _, err := cleanupScans(a.paths().ScansDir, 0); err != nil {
+		a.logWarning(fmt.Sprintf("failed to clean up scans: %v", err))
 	}
+
+	if n, err := a.purgeExpiredTrash(); err != nil {
+		a.logWarning(fmt.Sprintf("failed to purge trash: %v", err))
+	} else if n > 0 {
+		a.logInfo(fmt.Sprintf("purged %d expired sets from the trash", n))
+	}
+
+	// Hash photos stored before similarity search existed without delaying startup.
+	a.jobs.start(func(ctx context.Context) {
+		if n, err := a.backfillImageHashes(ctx); err != nil {
+			a.logWarning(fmt.Sprintf("failed to hash images: %v", err))
+		} else if n > 0 {
+			a.logInfo(fmt.Sprintf("hashed %d images for similarity search", n))
+		}
+	})
 }
 
-func (a *App) shutdown(ctx context.Context) {
-	if a.db != nil {
-		_ = a.db.Close()
+// backgroundJobs runs work that belongs to the open collection, so it can be
+// stopped before that collection is closed.
+type backgroundJobs struct {
+	mu     sync.Mutex
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func (j *backgroundJobs) start(fn func(ctx context.Context)) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.ctx == nil {
+		j.ctx, j.cancel = context.WithCancel(context.Background())
 	}
+	ctx := j.ctx
+	j.wg.Add(1)
+	go func() {
+		defer j.wg.Done()
+		fn(ctx)
+	}()
 }
 
-func resolveAppPaths() (AppPaths, error) {
-	configDir, err := os.UserConfigDir()
-	if err != nil {
-		return AppPaths{}, err
+// stop cancels the running jobs and waits for them to return.
+func (j *backgroundJobs) stop() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.cancel != nil {
+		j.cancel()
+		j.ctx, j.cancel = nil, nil
 	}
+	j.wg.Wait()
+}
 
-	base := filepath.Join(configDir, "Samla")
+// appPathsFor lays out the folders of a collection stored in base.
+func appPathsFor(base string) AppPaths {
 	dataDir := filepath.Join(base, "Data")
-	imagesDir := filepath.Join(base, "Images")
-	dbPath := filepath.Join(dataDir, "samla.db")
-
 	return AppPaths{
-		BaseDir:   base,
-		DataDir:   dataDir,
-		ImagesDir: imagesDir,
-		DBPath:    dbPath,
-	}, nil
+		BaseDir:       base,
+		DataDir:       dataDir,
+		ImagesDir:     filepath.Join(base, "Images"),
+		UndoDir:       filepath.Join(dataDir, "Undo"),
+		QuarantineDir: filepath.Join(dataDir, "Quarantine"),
+		ScansDir:      filepath.Join(dataDir, "Scans"),
+		DBPath:        filepath.Join(dataDir, "samla.db"),
+	}
 }
 
 func ensureDirs(paths AppPaths) error {
-	for _, dir := range []string{paths.BaseDir, paths.DataDir, paths.ImagesDir} {
+	for _, dir := range []string{paths.BaseDir, paths.DataDir, paths.ImagesDir, paths.UndoDir, paths.QuarantineDir, paths.ScansDir} {
 		if err := os.MkdirAll(dir, 0o755); err != nil {
 			return err
 		}
@@ -93,13 +225,15 @@ func ensureDirs(paths AppPaths) error {
 }
 
 func openDatabase(path string) (*sql.DB, error) {
-	db, err := sql.Open("sqlite", path)
+	// Foreign keys are enforced per connection, so the pragma goes into the
+	// DSN for the driver to apply to every connection in the pool.
+	db, err := sql.Open("sqlite", path+"?_pragma=foreign_keys(1)")
 	if err != nil {
 		return nil, err
 	}
 
-	// Enforce foreign keys and use WAL for better concurrent reads.
-	if _, err := db.Exec(`PRAGMA foreign_keys = ON; PRAGMA journal_mode = WAL;`); err != nil {
+	// Use WAL for better concurrent reads; it is stored in the database file.
+	if _, err := db.Exec(`PRAGMA journal_mode = WAL;`); err != nil {
 		db.Close()
 		return nil, fmt.Errorf("failed to apply pragmas: %w", err)
 	}
@@ -109,13 +243,14 @@ func openDatabase(path string) (*sql.DB, error) {
 
 // Utility: expose app folders to the UI.
 func (a *App) GetAppPaths() AppPaths {
-	return a.paths
+	return a.paths()
 }
 
 // Utility: open the base folder in the platform file explorer.
 func (a *App) OpenAppFolder() error {
-	if a.paths.BaseDir == "" {
+	base := a.paths().BaseDir
+	if base == "" {
 		return errors.New("paths not initialised")
 	}
-	return openFolder(a.paths.BaseDir)
+	return openFolder(base)
 }
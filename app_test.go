@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpenCollectionRemovesLeftovers(t *testing.T) {
+	dir := t.TempDir()
+	paths := appPathsFor(dir)
+	if err := ensureDirs(paths); err != nil {
+		t.Fatal(err)
+	}
+	leftovers := []string{
+		filepath.Join(paths.UndoDir, "held_photo.jpg"),
+		filepath.Join(paths.ScansDir, "scan.png"),
+	}
+	for _, p := range leftovers {
+		if err := os.WriteFile(p, []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// As at startup, no collection is open yet.
+	a := NewApp()
+	a.profiles.root = dir
+	if err := a.openCollection(paths); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		a.closeCollection(a.paths())
+		_ = a.db.Close()
+	})
+	for _, p := range leftovers {
+		if _, err := os.Stat(p); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("%s was not removed: %v", filepath.Base(p), err)
+		}
+	}
+}
+
+func TestFailedSwapKeepsCollection(t *testing.T) {
+	a := newTestApp(t)
+	setID := addTestSet(t, a, "kept")
+	if err := a.DeleteSet(setID); err != nil {
+		t.Fatal(err)
+	}
+	scan := filepath.Join(a.paths().ScansDir, "scan.png")
+	if err := os.WriteFile(scan, []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another swap is running, so opening a collection has to give up.
+	a.db.mu.Lock()
+	a.db.swapping = true
+	a.db.mu.Unlock()
+	err := a.openCollection(appPathsFor(t.TempDir()))
+	a.db.mu.Lock()
+	a.db.swapping = false
+	a.db.mu.Unlock()
+	if !errors.Is(err, errMaintenance) {
+		t.Fatalf("openCollection during a swap: %v", err)
+	}
+
+	if !a.GetUndoState().CanUndo {
+		t.Error("undo history was cleared although the collection stayed open")
+	}
+	if _, err := os.Stat(scan); err != nil {
+		t.Errorf("staged scan was removed: %v", err)
+	}
+	if _, err := a.Undo(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRemoveUnheldImagesKeepsHeldOnes(t *testing.T) {
+	a := newTestApp(t)
+	held := filepath.Join(a.paths().UndoDir, "held.jpg")
+	stale := filepath.Join(a.paths().UndoDir, "stale.jpg")
+	for _, p := range []string{held, stale} {
+		if err := os.WriteFile(p, []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	a.pushUndo(&undoAction{description: "test", images: []heldImage{{relPath: "Images/held.jpg", holdPath: held}}})
+
+	a.removeUnheldImages()
+	if _, err := os.Stat(held); err != nil {
+		t.Errorf("held image was removed: %v", err)
+	}
+	if _, err := os.Stat(stale); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("stale image was kept: %v", err)
+	}
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const (
+	// autoCropGrid is the number of cells along the long side of the scan
+	// used to find content. Finer grids follow the edges more closely but
+	// break a single sheet into more pieces.
+	autoCropGrid = 300
+	// autoCropTolerance is how far a pixel may differ from the background
+	// (per channel, 0-255) and still count as background.
+	autoCropTolerance = 40
+	// autoCropMinItem is the smallest item kept, as a fraction of the long side.
+	autoCropMinItem = 0.03
+	// autoCropGap joins pieces that are closer than this fraction of the long side.
+	autoCropGap = 0.02
+)
+
+// detectContent finds the items lying on the scanner glass. The background
+// colour is taken from the outer edge of the scan; every region that differs
+// from it and is large enough to not be dust becomes an item. Items close to
+// each other are merged, so a sheet with white gaps stays in one piece. The
+// rectangles are returned in reading order and are empty when nothing stands
+// out from the background.
+func detectContent(img image.Image) []image.Rectangle {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w < 8 || h < 8 {
+		return nil
+	}
+	cell := max(w, h) / autoCropGrid
+	if cell < 1 {
+		cell = 1
+	}
+	cols, rows := (w+cell-1)/cell, (h+cell-1)/cell
+	bg := backgroundColor(img)
+
+	// A cell holds content when a quarter of its samples differ from the background.
+	step := max(cell/4, 1)
+	mask := make([]bool, cols*rows)
+	for cy := 0; cy < rows; cy++ {
+		for cx := 0; cx < cols; cx++ {
+			total, differ := 0, 0
+			for y := cy * cell; y < min((cy+1)*cell, h); y += step {
+				for x := cx * cell; x < min((cx+1)*cell, w); x += step {
+					total++
+					if colorDistance(img.At(bounds.Min.X+x, bounds.Min.Y+y), bg) > autoCropTolerance {
+						differ++
+					}
+				}
+			}
+			mask[cy*cols+cx] = differ*4 >= total
+		}
+	}
+
+	boxes := maskComponents(mask, cols, rows)
+	gap := int(float64(max(cols, rows))*autoCropGap) + 1
+	boxes = mergeNearby(boxes, gap)
+
+	minSize := int(float64(max(cols, rows)) * autoCropMinItem)
+	var items []image.Rectangle
+	for _, b := range boxes {
+		if b.Dx() < minSize || b.Dy() < minSize {
+			continue
+		}
+		// One cell of margin so the crop does not cut into the item's edge.
+		r := image.Rect((b.Min.X-1)*cell, (b.Min.Y-1)*cell, (b.Max.X+1)*cell, (b.Max.Y+1)*cell)
+		r = r.Add(bounds.Min).Intersect(bounds)
+		if !r.Empty() {
+			items = append(items, r)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		// Items whose tops are within one gap count as the same row.
+		if d := items[i].Min.Y - items[j].Min.Y; d < -gap*cell || d > gap*cell {
+			return d < 0
+		}
+		return items[i].Min.X < items[j].Min.X
+	})
+	return items
+}
+
+// backgroundColor is the per-channel median of the pixels along the edge of the image.
+func backgroundColor(img image.Image) [3]uint8 {
+	b := img.Bounds()
+	var rs, gs, bs []uint8
+	sample := func(x, y int) {
+		c := color.RGBAModel.Convert(img.At(x, y)).(color.RGBA)
+		rs, gs, bs = append(rs, c.R), append(gs, c.G), append(bs, c.B)
+	}
+	stepX, stepY := max(b.Dx()/200, 1), max(b.Dy()/200, 1)
+	for x := b.Min.X; x < b.Max.X; x += stepX {
+		sample(x, b.Min.Y)
+		sample(x, b.Max.Y-1)
+	}
+	for y := b.Min.Y; y < b.Max.Y; y += stepY {
+		sample(b.Min.X, y)
+		sample(b.Max.X-1, y)
+	}
+	median := func(v []uint8) uint8 {
+		sort.Slice(v, func(i, j int) bool { return v[i] < v[j] })
+		return v[len(v)/2]
+	}
+	return [3]uint8{median(rs), median(gs), median(bs)}
+}
+
+// colorDistance is the largest channel difference between c and the background.
+func colorDistance(c color.Color, bg [3]uint8) int {
+	rgba := color.RGBAModel.Convert(c).(color.RGBA)
+	diff := func(a, b uint8) int {
+		if a > b {
+			return int(a - b)
+		}
+		return int(b - a)
+	}
+	return max(diff(rgba.R, bg[0]), diff(rgba.G, bg[1]), diff(rgba.B, bg[2]))
+}
+
+// maskComponents returns the bounding boxes, in cells, of the connected
+// content regions of mask.
+func maskComponents(mask []bool, cols, rows int) []image.Rectangle {
+	seen := make([]bool, len(mask))
+	var boxes []image.Rectangle
+	var queue []int
+	for start := range mask {
+		if !mask[start] || seen[start] {
+			continue
+		}
+		box := image.Rect(start%cols, start/cols, start%cols+1, start/cols+1)
+		seen[start] = true
+		queue = append(queue[:0], start)
+		for len(queue) > 0 {
+			i := queue[len(queue)-1]
+			queue = queue[:len(queue)-1]
+			x, y := i%cols, i/cols
+			box = box.Union(image.Rect(x, y, x+1, y+1))
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					nx, ny := x+dx, y+dy
+					if nx < 0 || ny < 0 || nx >= cols || ny >= rows {
+						continue
+					}
+					if j := ny*cols + nx; mask[j] && !seen[j] {
+						seen[j] = true
+						queue = append(queue, j)
+					}
+				}
+			}
+		}
+		boxes = append(boxes, box)
+	}
+	return boxes
+}
+
+// mergeNearby joins boxes that overlap once grown by gap until no more can be joined.
+func mergeNearby(boxes []image.Rectangle, gap int) []image.Rectangle {
+	for merged := true; merged; {
+		merged = false
+		for i := 0; i < len(boxes) && !merged; i++ {
+			grown := image.Rect(boxes[i].Min.X-gap, boxes[i].Min.Y-gap, boxes[i].Max.X+gap, boxes[i].Max.Y+gap)
+			for j := i + 1; j < len(boxes); j++ {
+				if grown.Overlaps(boxes[j]) {
+					boxes[i] = boxes[i].Union(boxes[j])
+					boxes = append(boxes[:j], boxes[j+1:]...)
+					merged = true
+					break
+				}
+			}
+		}
+	}
+	return boxes
+}
+
+// subImage returns the part of img inside r.
+func subImage(img image.Image, r image.Rectangle) image.Image {
+	if s, ok := img.(interface {
+		SubImage(image.Rectangle) image.Image
+	}); ok {
+		return s.SubImage(r)
+	}
+	dst := image.NewRGBA(r)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			dst.Set(x, y, img.At(x, y))
+		}
+	}
+	return dst
+}
+
+// ScanCrop is an automatically cropped part of a scan, staged under its own
+// token. X, Y, Width and Height locate it in the original scan in pixels.
+type ScanCrop struct {
+	Base64Data string `json:"base64Data"`
+	Token      string `json:"token"`
+	X          int    `json:"x"`
+	Y          int    `json:"y"`
+	Width      int    `json:"width"`
+	Height     int    `json:"height"`
+}
+
+// autoCropScan saves the content of a scan, and each item when there are
+// several, as scans of their own. cropped is nil when the content fills
+// nearly the whole glass or nothing was found; items is empty unless more
+// than one item was found.
+func (a *App) autoCropScan(token, format string) (cropped *ScanCrop, items []ScanCrop, err error) {
+	path, err := a.stagedScanPath(token)
+	if err != nil {
+		return nil, nil, err
+	}
+	img, err := decodeScan(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	rects := detectContent(img)
+	if len(rects) == 0 {
+		return nil, nil, nil
+	}
+	bounds := img.Bounds()
+	union := rects[0]
+	for _, r := range rects[1:] {
+		union = union.Union(r)
+	}
+	if union.Dx()*union.Dy()*100 < bounds.Dx()*bounds.Dy()*95 {
+		crop, err := a.saveScanCrop(img, union, format)
+		if err != nil {
+			return nil, nil, err
+		}
+		cropped = &crop
+	}
+	if len(rects) > 1 {
+		for _, r := range rects {
+			item, err := a.saveScanCrop(img, r, format)
+			if err != nil {
+				return nil, nil, err
+			}
+			items = append(items, item)
+		}
+	}
+	return cropped, items, nil
+}
+
+func (a *App) saveScanCrop(img image.Image, r image.Rectangle, format string) (ScanCrop, error) {
+	ext := ".png"
+	if format == "jpeg" {
+		ext = ".jpg"
+	}
+	token := newScanToken(ext)
+	fullPath := filepath.Join(a.paths().ScansDir, token)
+	if err := a.writeScanImage(fullPath, subImage(img, r), format); err != nil {
+		return ScanCrop{}, err
+	}
+	data, err := os.ReadFile(fullPath)
+	if err != nil {
+		return ScanCrop{}, err
+	}
+	origin := img.Bounds().Min
+	return ScanCrop{
+		Base64Data: dataURL(data),
+		Token:      token,
+		X:          r.Min.X - origin.X,
+		Y:          r.Min.Y - origin.Y,
+		Width:      r.Dx(),
+		Height:     r.Dy(),
+	}, nil
+}
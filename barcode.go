@@ -0,0 +1,189 @@
+package main
+
+import (
+	"errors"
+	"strings"
+)
+
+// BarcodeConflict is another set or product that already carries the same barcode.
+// Duplicates are allowed (the same product can be owned twice) but reported as a warning.
+type BarcodeConflict struct {
+	SetID       int64  `json:"setId"`
+	SetName     string `json:"setName"`
+	ProductID   int64  `json:"productId"`
+	ProductName string `json:"productName"`
+}
+
+// normalizeBarcode strips the spaces, dashes and control characters that
+// printed codes and keyboard-wedge scanners add around the digits. A UPC-A
+// code is stored in its EAN-13 form with a leading zero, so a product finds
+// the same set whichever of the two a scanner reports.
+func normalizeBarcode(code string) string {
+	code = strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' || r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, code)
+	if len(code) == 12 && isDigits(code) {
+		code = "0" + code
+	}
+	return code
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// isBarcodeQuery reports whether s looks like an EAN-8, UPC-A or EAN-13 code.
+func isBarcodeQuery(s string) bool {
+	switch len(s) {
+	case 8, 12, 13:
+		return isDigits(s)
+	}
+	return false
+}
+
+// validateBarcode checks length and GS1 check digit of an EAN-8, UPC-A, EAN-13 or GTIN-14 code.
+func validateBarcode(code string) error {
+	if !isDigits(code) {
+		return errors.New("barcode may only contain digits")
+	}
+	switch len(code) {
+	case 8, 12, 13, 14:
+	default:
+		return errors.New("barcode must have 8, 12, 13 or 14 digits")
+	}
+	sum := 0
+	for i := len(code) - 2; i >= 0; i-- {
+		d := int(code[i] - '0')
+		if (len(code)-2-i)%2 == 0 {
+			d *= 3
+		}
+		sum += d
+	}
+	if (10-sum%10)%10 != int(code[len(code)-1]-'0') {
+		return errors.New("barcode check digit is wrong")
+	}
+	return nil
+}
+
+// cleanBarcode normalizes and validates user input. An empty code is valid and clears the field.
+func cleanBarcode(code string) (string, error) {
+	code = normalizeBarcode(code)
+	if code == "" {
+		return "", nil
+	}
+	if err := validateBarcode(code); err != nil {
+		return "", err
+	}
+	return code, nil
+}
+
+// UpdateSetBarcode stores the barcode of a set and returns other sets and products using the same code.
+func (a *App) UpdateSetBarcode(setID int64, code string) ([]BarcodeConflict, error) {
+	code, err := cleanBarcode(code)
+	if err != nil {
+		return nil, err
+	}
+	res, err := a.db.Exec(`UPDATE sets SET barcode = NULLIF(?, '') WHERE id = ?`, code, setID)
+	if err != nil {
+		return nil, err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return nil, errors.New("set not found")
+	}
+	a.emitChange(EventSetUpdated, setID)
+	return a.barcodeConflicts(code, setID, 0)
+}
+
+// UpdateProductBarcode stores the barcode of a product and returns other sets and products using the same code.
+func (a *App) UpdateProductBarcode(productID int64, code string) ([]BarcodeConflict, error) {
+	code, err := cleanBarcode(code)
+	if err != nil {
+		return nil, err
+	}
+	res, err := a.db.Exec(`UPDATE elements SET barcode = NULLIF(?, '') WHERE id = ?`, code, productID)
+	if err != nil {
+		return nil, err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return nil, errors.New("product not found")
+	}
+	a.emitProductChange(productID)
+	return a.barcodeConflicts(code, 0, productID)
+}
+
+func (a *App) barcodeConflicts(code string, exceptSetID, exceptProductID int64) ([]BarcodeConflict, error) {
+	if code == "" {
+		return nil, nil
+	}
+	rows, err := a.db.Query(`
+		SELECT s.id, s.name, 0, '' FROM sets s
+		WHERE s.barcode = ? AND s.id != ? AND s.deleted_at IS NULL
+		UNION ALL
+		SELECT s.id, s.name, e.id, e.name FROM elements e
+		JOIN sets s ON s.id = e.set_id
+		WHERE e.barcode = ? AND e.id != ? AND s.deleted_at IS NULL
+		ORDER BY 2`, code, exceptSetID, code, exceptProductID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var conflicts []BarcodeConflict
+	for rows.Next() {
+		var c BarcodeConflict
+		if err := rows.Scan(&c.SetID, &c.SetName, &c.ProductID, &c.ProductName); err != nil {
+			return nil, err
+		}
+		conflicts = append(conflicts, c)
+	}
+	return conflicts, rows.Err()
+}
+
+// UpdateSetCatalogNumber stores the manufacturer's article number of a set.
+func (a *App) UpdateSetCatalogNumber(setID int64, catalogNo string) error {
+	catalogNo = normalizeName(catalogNo)
+	res, err := a.db.Exec(`UPDATE sets SET catalog_no = NULLIF(?, '') WHERE id = ?`, catalogNo, setID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return errors.New("set not found")
+	}
+	a.emitChange(EventSetUpdated, setID)
+	return nil
+}
+
+// LookupCode resolves text typed by a USB barcode scanner in keyboard mode.
+// A result without Kind means the code is not owned yet.
+func (a *App) LookupCode(code string) (LookupResult, error) {
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return LookupResult{}, errors.New("code is required")
+	}
+	if digits := normalizeBarcode(code); isDigits(digits) {
+		code = digits
+	}
+	return a.resolveCode(code, "keyboard")
+}
+
+// setIDByBarcode finds a set carrying the code itself or on one of its products.
+func (a *App) setIDByBarcode(code string) (int64, error) {
+	var setID int64
+	err := a.db.QueryRow(`
+		SELECT s.id FROM sets s
+		LEFT JOIN elements e ON e.set_id = s.id
+		WHERE s.deleted_at IS NULL AND (s.barcode = ? OR e.barcode = ?)
+		ORDER BY s.barcode = ? DESC, s.id
+		LIMIT 1`, code, code, code).Scan(&setID)
+	return setID, err
+}
@@ -0,0 +1,92 @@
+package main
+
+import "testing"
+
+func TestValidateBarcode(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		ok   bool
+	}{
+		{"EAN-8", "96385074", true},
+		{"EAN-8 wrong check digit", "96385075", false},
+		{"UPC-A", "036000291452", true},
+		{"UPC-A wrong check digit", "036000291453", false},
+		{"EAN-13", "4006381333931", true},
+		{"EAN-13 wrong check digit", "4006381333932", false},
+		{"EAN-13 swapped digits", "4003681333931", false},
+		{"UPC-A as EAN-13", "0036000291452", true},
+		{"GTIN-14", "10036000291459", true},
+		{"GTIN-14 wrong check digit", "10036000291450", false},
+		{"letters", "40063813339X1", false},
+		{"too short", "1234567", false},
+		{"between lengths", "1234567890", false},
+		{"too long", "123456789012345", false},
+		{"empty", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateBarcode(tt.code)
+			if tt.ok && err != nil {
+				t.Fatalf("validateBarcode(%q) = %v", tt.code, err)
+			}
+			if !tt.ok && err == nil {
+				t.Fatalf("validateBarcode(%q) accepted an invalid code", tt.code)
+			}
+		})
+	}
+}
+
+func TestNormalizeBarcode(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"4006381333931", "4006381333931"},
+		{"4 006381 333931", "4006381333931"},
+		{"4006381-333931\r\n", "4006381333931"},
+		{"96385074", "96385074"},
+		{"036000291452", "0036000291452"},
+		{"0 36000 29145 2", "0036000291452"},
+		{"0036000291452", "0036000291452"},
+		{"samla:set:12", "samla:set:12"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := normalizeBarcode(tt.in); got != tt.want {
+			t.Errorf("normalizeBarcode(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestUPCAMatchesEAN13(t *testing.T) {
+	a := newTestApp(t)
+	setID := addTestSet(t, a, "upc")
+	if _, err := a.UpdateSetBarcode(setID, "036000291452"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, code := range []string{"036000291452", "0036000291452"} {
+		result, err := a.LookupCode(code)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Kind != "set" || result.Set.ID != setID {
+			t.Fatalf("LookupCode(%q) = %+v", code, result)
+		}
+		found, err := a.SearchSets(code, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(found) != 1 || found[0].SetID != setID {
+			t.Fatalf("SearchSets(%q) = %+v", code, found)
+		}
+	}
+
+	conflicts, err := a.UpdateSetBarcode(addTestSet(t, a, "second"), "0036000291452")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conflicts) != 1 || conflicts[0].SetID != setID {
+		t.Fatalf("conflicts = %+v", conflicts)
+	}
+}
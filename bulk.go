@@ -0,0 +1,285 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// BulkResult reports the outcome of a bulk operation for a single set.
+// Bulk operations apply to each set on its own: sets with OK are changed,
+// while a set that fails is left exactly as it was and carries the reason in
+// Error. Either way the call commits once, so no other change sees a half
+// finished batch.
+type BulkResult struct {
+	SetID     int64  `json:"setId"`
+	OK        bool   `json:"ok"`
+	Error     string `json:"error"`
+	BagSerial string `json:"bagSerial,omitempty"`
+}
+
+var (
+	errSetNotFound = errors.New("set not found")
+	errSetInTrash  = errors.New("set is in the trash")
+)
+
+// bulkApply runs fn for every set inside one transaction. Each set gets its own
+// savepoint, so a failing set is rolled back and reported without affecting
+// the others; see BulkResult. Only an error of the transaction itself fails
+// the whole batch.
+func (a *App) bulkApply(setIDs []int64, fn func(tx *dbTx, setID int64) (string, error)) ([]BulkResult, error) {
+	if len(setIDs) == 0 {
+		return nil, errors.New("no sets selected")
+	}
+
+	tx, err := a.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	results := make([]BulkResult, 0, len(setIDs))
+	seen := make(map[int64]bool, len(setIDs))
+	for _, id := range setIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		if _, err = tx.Exec(`SAVEPOINT bulk_item`); err != nil {
+			return nil, err
+		}
+		serial, itemErr := fn(tx, id)
+		if itemErr != nil {
+			if _, err = tx.Exec(`ROLLBACK TO bulk_item`); err != nil {
+				return nil, err
+			}
+			results = append(results, BulkResult{SetID: id, Error: itemErr.Error()})
+		} else {
+			results = append(results, BulkResult{SetID: id, OK: true, BagSerial: serial})
+		}
+		if _, err = tx.Exec(`RELEASE bulk_item`); err != nil {
+			return nil, err
+		}
+	}
+
+	err = tx.Commit()
+	return results, err
+}
+
+// bulkUpdateSets runs bulkApply and reports the sets that changed.
+func (a *App) bulkUpdateSets(setIDs []int64, fn func(tx *dbTx, setID int64) (string, error)) ([]BulkResult, error) {
+	results, err := a.bulkApply(setIDs, fn)
+	if err != nil {
+		return nil, err
+	}
+	a.emitChange(EventSetUpdated, changedSets(results)...)
+	return results, nil
+}
+
+func setBagTx(tx *dbTx, setID int64) (int64, error) {
+	var bagID int64
+	err := tx.QueryRow(`SELECT bag_id FROM sets WHERE id = ?`, setID).Scan(&bagID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, errSetNotFound
+	}
+	return bagID, err
+}
+
+// BulkMoveSets moves sets into another box, giving each bag the next free
+// serial there. Sets in the trash are reported as failed and not moved.
+func (a *App) BulkMoveSets(setIDs []int64, boxID int64) ([]BulkResult, error) {
+	if boxID <= 0 {
+		return nil, errors.New("box is required")
+	}
+	var exists bool
+	if err := a.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM boxes WHERE id = ?)`, boxID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.New("box not found")
+	}
+
+	return a.bulkUpdateSets(setIDs, func(tx *dbTx, setID int64) (string, error) {
+		bagID, err := setBagTx(tx, setID)
+		if err != nil {
+			return "", err
+		}
+		var trashed bool
+		if err := tx.QueryRow(`SELECT deleted_at IS NOT NULL FROM sets WHERE id = ?`, setID).Scan(&trashed); err != nil {
+			return "", err
+		}
+		if trashed {
+			return "", errSetInTrash
+		}
+		var currentBox int64
+		var serial string
+		if err := tx.QueryRow(`SELECT box_id, serial_no FROM bags WHERE id = ?`, bagID).Scan(&currentBox, &serial); err != nil {
+			return "", err
+		}
+		if currentBox == boxID {
+			return serial, nil
+		}
+
+		serial, err = nextBagSerial(tx, boxID)
+		if err != nil {
+			return "", err
+		}
+		if _, err := tx.Exec(`UPDATE bags SET box_id = ?, serial_no = ? WHERE id = ?`, boxID, serial, bagID); err != nil {
+			return "", err
+		}
+		return serial, nil
+	})
+}
+
+// BulkAddTags adds the given tags to every set, keeping existing ones.
+func (a *App) BulkAddTags(setIDs []int64, tagNames []string) ([]BulkResult, error) {
+	return a.bulkUpdateSets(setIDs, func(tx *dbTx, setID int64) (string, error) {
+		if _, err := setBagTx(tx, setID); err != nil {
+			return "", err
+		}
+		for _, t := range tagNames {
+			tag := normalizeLower(t)
+			if tag == "" {
+				continue
+			}
+			tagID, err := ensureTagTx(tx, tag)
+			if err != nil {
+				return "", err
+			}
+			if _, err := tx.Exec(`INSERT OR IGNORE INTO set_tags(set_id, tag_id) VALUES (?, ?)`, setID, tagID); err != nil {
+				return "", err
+			}
+		}
+		return "", nil
+	})
+}
+
+// BulkRemoveTags removes the given tags from every set. The tags themselves are kept.
+func (a *App) BulkRemoveTags(setIDs []int64, tagNames []string) ([]BulkResult, error) {
+	return a.bulkUpdateSets(setIDs, func(tx *dbTx, setID int64) (string, error) {
+		if _, err := setBagTx(tx, setID); err != nil {
+			return "", err
+		}
+		for _, t := range tagNames {
+			tag := normalizeLower(t)
+			if tag == "" {
+				continue
+			}
+			if _, err := tx.Exec(`DELETE FROM set_tags WHERE set_id = ? AND tag_id IN (SELECT id FROM tags WHERE LOWER(name) = ?)`, setID, tag); err != nil {
+				return "", err
+			}
+		}
+		return "", nil
+	})
+}
+
+// BulkSetManufacturer assigns a manufacturer to every set. An empty name clears it.
+func (a *App) BulkSetManufacturer(setIDs []int64, manufacturerName string) ([]BulkResult, error) {
+	return a.bulkUpdateSets(setIDs, func(tx *dbTx, setID int64) (string, error) {
+		if _, err := setBagTx(tx, setID); err != nil {
+			return "", err
+		}
+		var manufacturerID sql.NullInt64
+		if manufacturerName != "" {
+			id, err := ensureManufacturerTx(tx, manufacturerName)
+			if err != nil {
+				return "", err
+			}
+			manufacturerID = sql.NullInt64{Int64: id, Valid: id > 0}
+		}
+		_, err := tx.Exec(`UPDATE sets SET manufacturer_id = ? WHERE id = ?`, manufacturerID, setID)
+		return "", err
+	})
+}
+
+// BulkSetType assigns a type to every set. An empty name clears it.
+func (a *App) BulkSetType(setIDs []int64, typeName string) ([]BulkResult, error) {
+	return a.bulkUpdateSets(setIDs, func(tx *dbTx, setID int64) (string, error) {
+		if _, err := setBagTx(tx, setID); err != nil {
+			return "", err
+		}
+		var typeID sql.NullInt64
+		if typeName != "" {
+			id, err := ensureTypeTx(tx, typeName)
+			if err != nil {
+				return "", err
+			}
+			typeID = sql.NullInt64{Int64: id, Valid: id > 0}
+		}
+		_, err := tx.Exec(`UPDATE sets SET type_id = ? WHERE id = ?`, typeID, setID)
+		return "", err
+	})
+}
+
+// BulkDeleteSets moves sets to the trash. The whole batch is undone as one step.
+func (a *App) BulkDeleteSets(setIDs []int64) ([]BulkResult, error) {
+	release, err := a.db.hold()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	act, results, err := a.bulkTrashSets(setIDs)
+	if err != nil {
+		return nil, err
+	}
+	if act != nil {
+		a.pushUndo(act)
+	}
+	a.emitChange(EventSetDeleted, changedSets(results)...)
+	return results, nil
+}
+
+func (a *App) bulkTrashSets(setIDs []int64) (*undoAction, []BulkResult, error) {
+	results, err := a.bulkApply(setIDs, func(tx *dbTx, setID int64) (string, error) {
+		if _, err := setBagTx(tx, setID); err != nil {
+			return "", err
+		}
+		res, err := tx.Exec(`UPDATE sets SET deleted_at = CURRENT_TIMESTAMP WHERE id = ? AND deleted_at IS NULL`, setID)
+		if err != nil {
+			return "", err
+		}
+		if n, err := res.RowsAffected(); err == nil && n == 0 {
+			return "", errors.New("set is already in the trash")
+		}
+		return "", nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var trashed []int64
+	for _, r := range results {
+		if r.OK {
+			trashed = append(trashed, r.SetID)
+		}
+	}
+	if len(trashed) == 0 {
+		return nil, results, nil
+	}
+
+	act := &undoAction{
+		description: fmt.Sprintf("Delete %d sets", len(trashed)),
+		revert: func(tx *dbTx) error {
+			for _, id := range trashed {
+				if _, err := tx.Exec(`UPDATE sets SET deleted_at = NULL WHERE id = ?`, id); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		redo: func() (*undoAction, error) {
+			act, _, err := a.bulkTrashSets(trashed)
+			if err == nil && act == nil {
+				err = errors.New("sets are already in the trash")
+			}
+			return act, err
+		},
+	}
+	return act, results, nil
+}
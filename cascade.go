@@ -0,0 +1,387 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// DeleteImpact lists everything that would be removed along with a box or location.
+type DeleteImpact struct {
+	Boxes    []Box       `json:"boxes"`
+	BagCount int         `json:"bagCount"`
+	Sets     []ImpactSet `json:"sets"`
+	Images   []string    `json:"images"`
+}
+
+// ImpactSet is a set that would disappear with a box or location.
+type ImpactSet struct {
+	SetID     int64  `json:"setId"`
+	SetName   string `json:"setName"`
+	BoxCode   string `json:"boxCode"`
+	BagSerial string `json:"bagSerial"`
+	Trashed   bool   `json:"trashed"`
+}
+
+// bagMove remembers where a bag was before it was moved to another box.
+type bagMove struct {
+	bagID    int64
+	boxID    int64
+	serialNo string
+}
+
+// PreviewDeleteLocation reports the boxes, bags, sets and images DeleteLocation would remove.
+func (a *App) PreviewDeleteLocation(id int64) (DeleteImpact, error) {
+	return a.deleteImpact(`location_id = ?`, id)
+}
+
+// PreviewDeleteBox reports the bags, sets and images DeleteBox would remove.
+func (a *App) PreviewDeleteBox(id int64) (DeleteImpact, error) {
+	return a.deleteImpact(`id = ?`, id)
+}
+
+func (a *App) deleteImpact(boxWhere string, args ...any) (DeleteImpact, error) {
+	var impact DeleteImpact
+
+	rows, err := a.db.Query(`SELECT id, location_id, code, IFNULL(name,''), IFNULL(serial_template,'') FROM boxes WHERE `+boxWhere+` ORDER BY code`, args...)
+	if err != nil {
+		return impact, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var b Box
+		if err := rows.Scan(&b.ID, &b.LocationID, &b.Code, &b.Name, &b.SerialTemplate); err != nil {
+			return impact, err
+		}
+		impact.Boxes = append(impact.Boxes, b)
+	}
+	if err := rows.Err(); err != nil {
+		return impact, err
+	}
+
+	boxIDs := `SELECT id FROM boxes WHERE ` + boxWhere
+	if err := a.db.QueryRow(`SELECT COUNT(*) FROM bags WHERE box_id IN (`+boxIDs+`)`, args...).Scan(&impact.BagCount); err != nil {
+		return impact, err
+	}
+
+	setRows, err := a.db.Query(`
+		SELECT s.id, s.name, bx.code, b.serial_no, s.deleted_at IS NOT NULL, IFNULL(s.photo_path,'')
+		FROM sets s
+		JOIN bags b ON b.id = s.bag_id
+		JOIN boxes bx ON bx.id = b.box_id
+		WHERE bx.id IN (`+boxIDs+`)
+		ORDER BY bx.code, b.serial_no`, args...)
+	if err != nil {
+		return impact, err
+	}
+	defer setRows.Close()
+	for setRows.Next() {
+		var s ImpactSet
+		var photo string
+		if err := setRows.Scan(&s.SetID, &s.SetName, &s.BoxCode, &s.BagSerial, &s.Trashed, &photo); err != nil {
+			return impact, err
+		}
+		impact.Sets = append(impact.Sets, s)
+		if photo != "" {
+			impact.Images = append(impact.Images, photo)
+		}
+	}
+	return impact, setRows.Err()
+}
+
+func (a *App) deleteLocation(id, targetLocationID int64, confirmCascade bool) (*undoAction, error) {
+	if targetLocationID == id {
+		return nil, errors.New("cannot move boxes into the location being deleted")
+	}
+
+	tx, err := a.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var name string
+	if err = tx.QueryRow(`SELECT friendly_name FROM storage_locations WHERE id = ?`, id).Scan(&name); err != nil {
+		return nil, err
+	}
+	loc, err := snapshotRows(tx, "storage_locations", `id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+
+	act := &undoAction{
+		description: fmt.Sprintf("Delete location %q", name),
+		snapshots:   []tableSnapshot{loc},
+		redo: func() (*undoAction, error) {
+			return a.deleteLocation(id, targetLocationID, confirmCascade)
+		},
+	}
+
+	var photos []string
+	if targetLocationID > 0 {
+		var exists bool
+		if err = tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM storage_locations WHERE id = ?)`, targetLocationID).Scan(&exists); err != nil {
+			return nil, err
+		}
+		if !exists {
+			err = errors.New("target location not found")
+			return nil, err
+		}
+
+		var boxIDs, setIDs []int64
+		if boxIDs, err = listIDsTx(tx, `SELECT id FROM boxes WHERE location_id = ?`, id); err != nil {
+			return nil, err
+		}
+		if setIDs, err = listIDsTx(tx, setsInBoxesOf, id); err != nil {
+			return nil, err
+		}
+		if _, err = tx.Exec(`UPDATE boxes SET location_id = ? WHERE location_id = ?`, targetLocationID, id); err != nil {
+			return nil, err
+		}
+		act.changes = []pendingChange{{EventBoxUpdated, boxIDs}, {EventSetUpdated, setIDs}}
+		act.revert = func(tx *dbTx) error {
+			for _, boxID := range boxIDs {
+				if _, err := tx.Exec(`UPDATE boxes SET location_id = ? WHERE id = ?`, id, boxID); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	} else {
+		var boxCount int
+		if err = tx.QueryRow(`SELECT COUNT(*) FROM boxes WHERE location_id = ?`, id).Scan(&boxCount); err != nil {
+			return nil, err
+		}
+		if boxCount > 0 && !confirmCascade {
+			err = fmt.Errorf("location %q still contains %d boxes; move them to another location or confirm the deletion", name, boxCount)
+			return nil, err
+		}
+
+		var boxIDs, setIDs []int64
+		if boxIDs, err = listIDsTx(tx, `SELECT id FROM boxes WHERE location_id = ?`, id); err != nil {
+			return nil, err
+		}
+		if setIDs, err = listIDsTx(tx, setsInBoxesOf, id); err != nil {
+			return nil, err
+		}
+		act.changes = []pendingChange{{EventBoxDeleted, boxIDs}, {EventSetPurged, setIDs}}
+
+		var boxes tableSnapshot
+		if boxes, err = snapshotRows(tx, "boxes", `location_id = ?`, id); err != nil {
+			return nil, err
+		}
+		bagWhere := `box_id IN (SELECT id FROM boxes WHERE location_id = ?)`
+		var bags []tableSnapshot
+		if bags, err = snapshotBagTree(tx, bagWhere, id); err != nil {
+			return nil, err
+		}
+		if photos, err = photoPathsTx(tx, bagWhere, id); err != nil {
+			return nil, err
+		}
+		act.snapshots = append(act.snapshots, boxes)
+		act.snapshots = append(act.snapshots, bags...)
+	}
+
+	if _, err = tx.Exec(`DELETE FROM storage_locations WHERE id = ?`, id); err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	a.holdImages(act, photos)
+	return act, nil
+}
+
+func (a *App) deleteBox(id, targetBoxID int64, confirmCascade bool) (*undoAction, error) {
+	if targetBoxID == id {
+		return nil, errors.New("cannot move bags into the box being deleted")
+	}
+
+	tx, err := a.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var code string
+	if err = tx.QueryRow(`SELECT code FROM boxes WHERE id = ?`, id).Scan(&code); err != nil {
+		return nil, err
+	}
+	box, err := snapshotRows(tx, "boxes", `id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+
+	act := &undoAction{
+		description: fmt.Sprintf("Delete box %q", code),
+		snapshots:   []tableSnapshot{box},
+		redo: func() (*undoAction, error) {
+			return a.deleteBox(id, targetBoxID, confirmCascade)
+		},
+	}
+
+	var photos []string
+	if targetBoxID > 0 {
+		var exists bool
+		if err = tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM boxes WHERE id = ?)`, targetBoxID).Scan(&exists); err != nil {
+			return nil, err
+		}
+		if !exists {
+			err = errors.New("target box not found")
+			return nil, err
+		}
+
+		var setIDs []int64
+		if setIDs, err = listIDsTx(tx, setsInBox, id); err != nil {
+			return nil, err
+		}
+		var moves []bagMove
+		if moves, err = moveBagsTx(tx, id, targetBoxID); err != nil {
+			return nil, err
+		}
+		act.changes = []pendingChange{{EventBoxUpdated, []int64{targetBoxID}}, {EventSetUpdated, setIDs}}
+		act.revert = func(tx *dbTx) error {
+			for _, m := range moves {
+				if _, err := tx.Exec(`UPDATE bags SET box_id = ?, serial_no = ? WHERE id = ?`, m.boxID, m.serialNo, m.bagID); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	} else {
+		var bagCount int
+		if err = tx.QueryRow(`SELECT COUNT(*) FROM bags WHERE box_id = ?`, id).Scan(&bagCount); err != nil {
+			return nil, err
+		}
+		if bagCount > 0 && !confirmCascade {
+			err = fmt.Errorf("box %q still contains %d bags; move them to another box or confirm the deletion", code, bagCount)
+			return nil, err
+		}
+
+		var setIDs []int64
+		if setIDs, err = listIDsTx(tx, setsInBox, id); err != nil {
+			return nil, err
+		}
+		act.changes = []pendingChange{{EventSetPurged, setIDs}}
+
+		var bags []tableSnapshot
+		if bags, err = snapshotBagTree(tx, `box_id = ?`, id); err != nil {
+			return nil, err
+		}
+		if photos, err = photoPathsTx(tx, `box_id = ?`, id); err != nil {
+			return nil, err
+		}
+		act.snapshots = append(act.snapshots, bags...)
+	}
+
+	if _, err = tx.Exec(`DELETE FROM boxes WHERE id = ?`, id); err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	a.holdImages(act, photos)
+	return act, nil
+}
+
+// moveBagsTx moves every bag of one box into another. Bags keep their serial
+// unless it is already taken in the target box, in which case they get the next free one.
+func moveBagsTx(tx *dbTx, fromBoxID, toBoxID int64) ([]bagMove, error) {
+	rows, err := tx.Query(`SELECT id, box_id, serial_no FROM bags WHERE box_id = ? ORDER BY serial_no`, fromBoxID)
+	if err != nil {
+		return nil, err
+	}
+	var moves []bagMove
+	for rows.Next() {
+		var m bagMove
+		if err := rows.Scan(&m.bagID, &m.boxID, &m.serialNo); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		moves = append(moves, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, m := range moves {
+		if _, err := moveBagTx(tx, m.bagID, m.serialNo, toBoxID); err != nil {
+			return nil, err
+		}
+	}
+	return moves, nil
+}
+
+// moveBagTx puts a bag into a box, keeping its serial when it is free there.
+func moveBagTx(tx *dbTx, bagID int64, serialNo string, toBoxID int64) (string, error) {
+	var taken bool
+	if err := tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM bags WHERE box_id = ? AND serial_no = ? AND id <> ?)`, toBoxID, serialNo, bagID).Scan(&taken); err != nil {
+		return "", err
+	}
+	if taken {
+		next, err := nextBagSerial(tx, toBoxID)
+		if err != nil {
+			return "", err
+		}
+		serialNo = next
+	}
+	if _, err := tx.Exec(`UPDATE bags SET box_id = ?, serial_no = ? WHERE id = ?`, toBoxID, serialNo, bagID); err != nil {
+		return "", err
+	}
+	return serialNo, nil
+}
+
+// photoPathsTx returns the photo and original image paths of all sets in bags matching bagWhere.
+func photoPathsTx(tx *dbTx, bagWhere string, args ...any) ([]string, error) {
+	rows, err := tx.Query(`
+		SELECT photo_path FROM sets WHERE bag_id IN (SELECT id FROM bags WHERE `+bagWhere+`) AND IFNULL(photo_path,'') <> ''
+		UNION
+		SELECT original_path FROM sets WHERE bag_id IN (SELECT id FROM bags WHERE `+bagWhere+`) AND IFNULL(original_path,'') <> ''`, append(append([]any{}, args...), args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var paths []string
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			return nil, err
+		}
+		paths = append(paths, p)
+	}
+	return paths, rows.Err()
+}
+
+// setsInBox and setsInBoxesOf list the sets, trashed ones included, in a box
+// or in the boxes of a location.
+const (
+	setsInBox     = `SELECT s.id FROM sets s JOIN bags b ON b.id = s.bag_id WHERE b.box_id = ?`
+	setsInBoxesOf = `SELECT s.id FROM sets s JOIN bags b ON b.id = s.bag_id JOIN boxes bx ON bx.id = b.box_id WHERE bx.location_id = ?`
+)
+
+func listIDsTx(tx *dbTx, query string, args ...any) ([]int64, error) {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
@@ -0,0 +1,371 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+const (
+	// locationFileName in the default folder points to a data folder elsewhere.
+	locationFileName = "location.json"
+	// portableMarker next to the executable switches to portable mode, which
+	// keeps all data in portableDirName beside it.
+	portableMarker  = "samla.portable"
+	portableDirName = "SamlaData"
+	dataDirFlag     = "--data-dir"
+
+	// EventDataFolderMoved is emitted after MoveDataFolder opened the collection from its new place.
+	EventDataFolderMoved = "data:moved"
+)
+
+// DataLocation describes where the data folder is and how it was chosen.
+// Mode is "default", "custom" (set with MoveDataFolder), "flag" (--data-dir)
+// or "portable". Only the default and custom locations can be moved.
+type DataLocation struct {
+	BaseDir string `json:"baseDir"`
+	Mode    string `json:"mode"`
+	CanMove bool   `json:"canMove"`
+}
+
+type locationFile struct {
+	DataDir string `json:"dataDir"`
+}
+
+// resolveRootDir returns the folder holding the profiles and how it was
+// chosen: the --data-dir flag wins over portable mode, which wins over a
+// folder chosen with MoveDataFolder.
+func resolveRootDir() (string, string, error) {
+	if dir := dataDirFromArgs(os.Args[1:]); dir != "" {
+		abs, err := filepath.Abs(dir)
+		return abs, "flag", err
+	}
+	if exe, err := os.Executable(); err == nil {
+		exeDir := filepath.Dir(exe)
+		if _, err := os.Stat(filepath.Join(exeDir, portableMarker)); err == nil {
+			return filepath.Join(exeDir, portableDirName), "portable", nil
+		}
+	}
+	def, err := defaultRootDir()
+	if err != nil {
+		return "", "", err
+	}
+	data, err := os.ReadFile(filepath.Join(def, locationFileName))
+	if err == nil {
+		var loc locationFile
+		if err := json.Unmarshal(data, &loc); err != nil {
+			return "", "", fmt.Errorf("failed to read %s: %w", locationFileName, err)
+		}
+		if loc.DataDir != "" {
+			return loc.DataDir, "custom", nil
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return "", "", err
+	}
+	return def, "default", nil
+}
+
+func defaultRootDir() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "Samla"), nil
+}
+
+// dataDirFromArgs finds --data-dir=<path> or --data-dir <path>.
+func dataDirFromArgs(args []string) string {
+	for i, arg := range args {
+		if v, ok := strings.CutPrefix(arg, dataDirFlag+"="); ok {
+			return v
+		}
+		if arg == dataDirFlag && i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+func (a *App) GetDataLocation() DataLocation {
+	a.profiles.mu.Lock()
+	defer a.profiles.mu.Unlock()
+	return a.dataLocation()
+}
+
+// dataLocation is GetDataLocation for callers holding the profiles lock.
+func (a *App) dataLocation() DataLocation {
+	mode := a.profiles.mode
+	return DataLocation{BaseDir: a.profiles.root, Mode: mode, CanMove: mode == "default" || mode == "custom"}
+}
+
+// ChooseDataFolder opens a folder dialog to pick a new data folder.
+func (a *App) ChooseDataFolder() (string, error) {
+	return runtime.OpenDirectoryDialog(a.ctx, runtime.OpenDialogOptions{
+		Title:                "Datenordner auswählen / Choose Data Folder",
+		CanCreateDirectories: true,
+	})
+}
+
+// MoveDataFolder moves the database and images of all profiles to target,
+// which must be empty or missing, and remembers the new place for the next
+// start. Data is copied first and only removed from the old place once the
+// collection has been opened from the new one.
+func (a *App) MoveDataFolder(target string) (DataLocation, error) {
+	r := &a.profiles
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.mode != "default" && r.mode != "custom" {
+		return DataLocation{}, fmt.Errorf("the data folder is fixed in %s mode", r.mode)
+	}
+	target = strings.TrimSpace(target)
+	if target == "" || !filepath.IsAbs(target) {
+		return DataLocation{}, errors.New("please choose a full folder path")
+	}
+	target = filepath.Clean(target)
+	oldRoot := filepath.Clean(r.root)
+	if target == oldRoot {
+		return a.dataLocation(), nil
+	}
+	if isWithin(oldRoot, target) || isWithin(target, oldRoot) {
+		return DataLocation{}, errors.New("the new data folder cannot be inside the current one or contain it")
+	}
+	def, err := defaultRootDir()
+	if err != nil {
+		return DataLocation{}, err
+	}
+	if err := checkEmptyDir(target); err != nil {
+		return DataLocation{}, err
+	}
+
+	f, err := r.load()
+	if err != nil {
+		return DataLocation{}, err
+	}
+	oldPaths := a.paths()
+	newPaths := appPathsFor(filepath.Join(target, profileSubdir(f.Active)))
+	newMode := "custom"
+	if target == def {
+		newMode = "default"
+	}
+
+	// The collection is closed first so the database is complete on disk before copying.
+	err = a.replaceCollection(func(AppPaths) (*sql.DB, AppPaths, error) {
+		restore := func(err error) (*sql.DB, AppPaths, error) {
+			_ = removeCopied(target)
+			_ = writeLocation(def, oldRoot)
+			db, reopenErr := a.prepareDatabase(oldPaths)
+			if reopenErr != nil {
+				return nil, oldPaths, fmt.Errorf("%v (and the collection could not be reopened: %v)", err, reopenErr)
+			}
+			return db, oldPaths, err
+		}
+
+		if err := copyTree(oldRoot, target); err != nil {
+			return restore(fmt.Errorf("failed to copy data: %w", err))
+		}
+		for _, p := range f.Profiles {
+			oldBase := filepath.Join(oldRoot, profileSubdir(p.ID))
+			newBase := filepath.Join(target, profileSubdir(p.ID))
+			if err := rewriteAbsoluteImagePaths(appPathsFor(newBase).DBPath, oldBase); err != nil {
+				return restore(fmt.Errorf("failed to update image paths: %w", err))
+			}
+		}
+		if err := writeLocation(def, target); err != nil {
+			return restore(err)
+		}
+		db, err := a.prepareDatabase(newPaths)
+		if err != nil {
+			return restore(err)
+		}
+		r.root, r.mode = target, newMode
+		return db, newPaths, nil
+	})
+	if err != nil {
+		return DataLocation{}, err
+	}
+
+	// The old place keeps only the pointer to the new one, if it is the default folder.
+	if oldRoot == def {
+		err = removeCopied(oldRoot)
+	} else {
+		err = os.RemoveAll(oldRoot)
+	}
+	if err != nil {
+		a.logWarning(fmt.Sprintf("failed to remove old data folder: %v", err))
+	}
+
+	location := a.dataLocation()
+	a.emitEvent(EventDataFolderMoved, location)
+	return location, nil
+}
+
+// writeLocation points the default folder at dataDir, or removes the
+// pointer when the data lives in the default folder itself.
+func writeLocation(def, dataDir string) error {
+	path := filepath.Join(def, locationFileName)
+	if dataDir == def {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	if err := os.MkdirAll(def, 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(locationFile{DataDir: dataDir}, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// checkEmptyDir accepts a missing folder or one holding nothing but the location pointer.
+func checkEmptyDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.Name() != locationFileName {
+			return errors.New("the new data folder must be empty")
+		}
+	}
+	return nil
+}
+
+// copyTree copies src into dst, leaving out the location pointer.
+func copyTree(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if rel == locationFileName {
+			return nil
+		}
+		out := filepath.Join(dst, rel)
+		if d.IsDir() {
+			return os.MkdirAll(out, 0o755)
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		return copyFile(path, out)
+	})
+}
+
+// removeCopied empties dir except for the location pointer.
+func removeCopied(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.Name() == locationFileName {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(dir, e.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rewriteAbsoluteImagePaths turns absolute image paths inside oldBase into
+// paths relative to the data folder, so they survive the move.
+func rewriteAbsoluteImagePaths(dbPath, oldBase string) error {
+	if _, err := os.Stat(dbPath); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	db, err := openDatabase(dbPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	for _, q := range []struct{ table, column string }{
+		{"sets", "photo_path"},
+		{"sets", "original_path"},
+		{"image_hashes", "path"},
+	} {
+		if err = rewriteColumn(tx, q.table, q.column, oldBase); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func rewriteColumn(tx *sql.Tx, table, column, oldBase string) error {
+	rows, err := tx.Query(fmt.Sprintf(`SELECT DISTINCT %s FROM %s WHERE %s IS NOT NULL AND %s <> ''`, column, table, column, column))
+	if err != nil {
+		return err
+	}
+	var paths []string
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			rows.Close()
+			return err
+		}
+		if filepath.IsAbs(p) && isWithin(oldBase, p) {
+			paths = append(paths, p)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, p := range paths {
+		rel, err := filepath.Rel(oldBase, p)
+		if err != nil {
+			return err
+		}
+		query := fmt.Sprintf(`UPDATE %s SET %s = ? WHERE %s = ?`, table, column, column)
+		if table == "image_hashes" {
+			// The relative path may already have a hash of its own.
+			query = `UPDATE OR REPLACE image_hashes SET path = ? WHERE path = ?`
+		}
+		if _, err := tx.Exec(query, filepath.ToSlash(rel), p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isWithin reports whether path is dir or lies below it.
+func isWithin(dir, path string) bool {
+	rel, err := filepath.Rel(filepath.Clean(dir), filepath.Clean(path))
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -7,6 +7,12 @@ import (
 type migration struct {
 	version    int
 	statements []string
+	// run handles data that SQL alone cannot migrate, such as files on disk.
+	// It is called after the schema changes of all migrations have been
+	// committed, and the version is only recorded once it succeeds, so a
+	// failed run is retried on the next start. The statements of such a
+	// migration are then applied again and must be safe to repeat.
+	run func(a *App) error
 }
 
 var migrations = []migration{
@@ -96,10 +102,73 @@ var migrations = []migration{
 			`ALTER TABLE storage_locations ADD COLUMN compartment TEXT;`,
 		},
 	},
+	{
+		version: 4,
+		statements: []string{
+			`ALTER TABLE sets ADD COLUMN deleted_at TEXT;`,
+			`CREATE INDEX IF NOT EXISTS idx_sets_deleted_at ON sets(deleted_at);`,
+			`CREATE TABLE IF NOT EXISTS settings (
+				key TEXT PRIMARY KEY,
+				value TEXT NOT NULL
+			);`,
+		},
+	},
+	{
+		version: 5,
+		statements: []string{
+			`ALTER TABLE boxes ADD COLUMN serial_template TEXT;`,
+		},
+	},
+	{
+		version: 6,
+		statements: []string{
+			`ALTER TABLE sets ADD COLUMN barcode TEXT;`,
+			`ALTER TABLE elements ADD COLUMN barcode TEXT;`,
+			`CREATE INDEX IF NOT EXISTS idx_sets_barcode ON sets(barcode);`,
+			`CREATE INDEX IF NOT EXISTS idx_elements_barcode ON elements(barcode);`,
+		},
+	},
+	{
+		version: 7,
+		statements: []string{
+			`ALTER TABLE sets ADD COLUMN catalog_no TEXT;`,
+			`CREATE INDEX IF NOT EXISTS idx_sets_catalog_no ON sets(catalog_no);`,
+		},
+	},
+	{
+		version: 8,
+		statements: []string{
+			`CREATE TABLE IF NOT EXISTS image_hashes (
+				path TEXT PRIMARY KEY,
+				dhash INTEGER NOT NULL,
+				phash INTEGER NOT NULL
+			);`,
+		},
+	},
+	{
+		version: 9,
+		run: func(a *App) error {
+			_, err := a.dedupeImages()
+			return err
+		},
+	},
+	{
+		version: 10,
+		statements: []string{
+			`ALTER TABLE sets ADD COLUMN original_path TEXT;`,
+		},
+	},
+	{
+		version: 11,
+		statements: []string{
+			`UPDATE sets SET barcode = '0' || barcode WHERE length(barcode) = 12 AND barcode NOT GLOB '*[^0-9]*';`,
+			`UPDATE elements SET barcode = '0' || barcode WHERE length(barcode) = 12 AND barcode NOT GLOB '*[^0-9]*';`,
+		},
+	},
 }
 
 func (a *App) runMigrations() error {
-	if a.db == nil {
+	if !a.db.isOpen() {
 		return fmt.Errorf("database not initialised")
 	}
 
@@ -120,13 +189,16 @@ func (a *App) runMigrations() error {
 		return fmt.Errorf("init migration table: %w", err)
 	}
 
-	var current int
-	if err = tx.QueryRow(`SELECT IFNULL(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+	// Versions are checked one by one rather than against the highest, so a
+	// migration whose run failed is not hidden by later ones.
+	applied, err := appliedMigrations(tx)
+	if err != nil {
 		return fmt.Errorf("read migration version: %w", err)
 	}
 
+	var pending []migration
 	for _, m := range migrations {
-		if m.version <= current {
+		if applied[m.version] {
 			continue
 		}
 
@@ -136,11 +208,43 @@ func (a *App) runMigrations() error {
 			}
 		}
 
+		if m.run != nil {
+			pending = append(pending, m)
+			continue
+		}
 		if _, err = tx.Exec(`INSERT INTO schema_migrations(version) VALUES (?)`, m.version); err != nil {
 			return fmt.Errorf("record migration %d: %w", m.version, err)
 		}
 	}
 
-	err = tx.Commit()
-	return err
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	for _, m := range pending {
+		if err := m.run(a); err != nil {
+			return fmt.Errorf("apply migration %d: %w", m.version, err)
+		}
+		if _, err := a.db.Exec(`INSERT INTO schema_migrations(version) VALUES (?)`, m.version); err != nil {
+			return fmt.Errorf("record migration %d: %w", m.version, err)
+		}
+	}
+	return nil
+}
+
+func appliedMigrations(q queryer) (map[int]bool, error) {
+	rows, err := q.Query(`SELECT version FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	applied := make(map[int]bool)
+	for rows.Next() {
+		var v int
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		applied[v] = true
+	}
+	return applied, rows.Err()
 }
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+)
+
+// dbSwapTimeout is how long a swap waits for running queries and
+// transactions of the old database before giving up.
+const dbSwapTimeout = 10 * time.Second
+
+var (
+	errMaintenance    = errors.New("maintenance in progress - please try again in a moment")
+	errDatabaseClosed = errors.New("no collection is open")
+	errDatabaseBusy   = errors.New("the database is still busy - please try again")
+)
+
+// dbHandle guards the database and the folders of the open collection so
+// both can be replaced while the app runs, for example by an import or a
+// profile switch. Every statement, query and transaction is counted while it
+// runs; rows count until they are closed or read to the end, transactions
+// until they commit or roll back. swap waits for the count to drop to zero
+// before it replaces the database. Calls made while a swap is running fail
+// with errMaintenance instead of waiting, so code that holds a transaction
+// and calls back into the handle cannot block the swap.
+type dbHandle struct {
+	mu       sync.Mutex
+	db       *sql.DB
+	paths    AppPaths
+	inUse    int
+	swapping bool
+	idle     chan struct{} // closed once inUse drops to zero during a swap
+}
+
+func newDBHandle(db *sql.DB, paths AppPaths) *dbHandle {
+	return &dbHandle{db: db, paths: paths}
+}
+
+// acquire returns the database and counts a use until release is called.
+func (h *dbHandle) acquire() (*sql.DB, func(), error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.swapping {
+		return nil, nil, errMaintenance
+	}
+	if h.db == nil {
+		return nil, nil, errDatabaseClosed
+	}
+	h.inUse++
+	var once sync.Once
+	return h.db, func() { once.Do(h.release) }, nil
+}
+
+func (h *dbHandle) release() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.inUse--
+	if h.inUse == 0 && h.idle != nil {
+		close(h.idle)
+		h.idle = nil
+	}
+}
+
+// hold counts a use of the database without running anything, so a swap
+// waits until release is called. Calls that record an undo step hold the
+// handle until the step is pushed; a swap then clears the step together with
+// the rest of the history instead of letting it reach the next collection.
+func (h *dbHandle) hold() (release func(), err error) {
+	_, release, err = h.acquire()
+	return release, err
+}
+
+// isOpen reports whether a database is in place.
+func (h *dbHandle) isOpen() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.db != nil
+}
+
+// currentPaths returns the folders of the open collection.
+func (h *dbHandle) currentPaths() AppPaths {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.paths
+}
+
+func (h *dbHandle) Exec(query string, args ...any) (sql.Result, error) {
+	db, release, err := h.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return db.Exec(query, args...)
+}
+
+// Query runs a query; swap waits for the returned rows to be closed.
+func (h *dbHandle) Query(query string, args ...any) (*dbRows, error) {
+	db, release, err := h.acquire()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	return &dbRows{Rows: rows, release: release}, nil
+}
+
+// QueryRow runs a query for a single row; swap waits until it is scanned.
+func (h *dbHandle) QueryRow(query string, args ...any) *dbRow {
+	db, release, err := h.acquire()
+	if err != nil {
+		return &dbRow{err: err}
+	}
+	return &dbRow{row: db.QueryRow(query, args...), release: release}
+}
+
+// Begin starts a transaction; swap waits for it to commit or roll back.
+func (h *dbHandle) Begin() (*dbTx, error) {
+	db, release, err := h.acquire()
+	if err != nil {
+		return nil, err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		release()
+		return nil, err
+	}
+	return &dbTx{Tx: tx, release: release}, nil
+}
+
+// Close closes the database; later calls fail with errDatabaseClosed.
+func (h *dbHandle) Close() error {
+	return h.swap(func(old *sql.DB, paths AppPaths) (*sql.DB, AppPaths, error) {
+		if old == nil {
+			return nil, paths, nil
+		}
+		return nil, paths, old.Close()
+	})
+}
+
+// swap replaces the database and folders with the ones returned by replace.
+// It stops new calls, waits for running ones to finish and then runs
+// replace, which is responsible for closing old. The returned database is
+// installed even when replace also returns an error, so a failed import can
+// still hand back a usable database; returning nil leaves no database open.
+func (h *dbHandle) swap(replace func(old *sql.DB, paths AppPaths) (*sql.DB, AppPaths, error)) error {
+	h.mu.Lock()
+	if h.swapping {
+		h.mu.Unlock()
+		return errMaintenance
+	}
+	h.swapping = true
+	var idle chan struct{}
+	if h.inUse > 0 {
+		h.idle = make(chan struct{})
+		idle = h.idle
+	}
+	old, paths := h.db, h.paths
+	h.mu.Unlock()
+
+	if idle != nil {
+		timer := time.NewTimer(dbSwapTimeout)
+		defer timer.Stop()
+		select {
+		case <-idle:
+		case <-timer.C:
+			h.mu.Lock()
+			h.swapping, h.idle = false, nil
+			h.mu.Unlock()
+			return errDatabaseBusy
+		}
+	}
+
+	db, paths, err := replace(old, paths)
+	h.mu.Lock()
+	h.db, h.paths, h.swapping = db, paths, false
+	h.mu.Unlock()
+	return err
+}
+
+// dbRows are rows from the handle. They stop counting as in use once closed
+// or read to the end.
+type dbRows struct {
+	*sql.Rows
+	release func()
+}
+
+func (r *dbRows) Next() bool {
+	if r.Rows.Next() {
+		return true
+	}
+	r.done()
+	return false
+}
+
+func (r *dbRows) Close() error {
+	err := r.Rows.Close()
+	r.done()
+	return err
+}
+
+func (r *dbRows) done() {
+	if r.release != nil {
+		r.release()
+	}
+}
+
+// dbRow is the result of QueryRow. As with *sql.Row, errors are reported by Scan.
+type dbRow struct {
+	row     *sql.Row
+	err     error
+	release func()
+}
+
+func (r *dbRow) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+	if r.release != nil {
+		defer r.release()
+	}
+	return r.row.Scan(dest...)
+}
+
+// dbTx is a transaction from the handle. Its queries return the same types
+// as the handle, so both satisfy queryer.
+type dbTx struct {
+	*sql.Tx
+	release func()
+}
+
+func (tx *dbTx) Commit() error {
+	defer tx.release()
+	return tx.Tx.Commit()
+}
+
+func (tx *dbTx) Rollback() error {
+	defer tx.release()
+	return tx.Tx.Rollback()
+}
+
+func (tx *dbTx) Query(query string, args ...any) (*dbRows, error) {
+	rows, err := tx.Tx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return &dbRows{Rows: rows}, nil
+}
+
+func (tx *dbTx) QueryRow(query string, args ...any) *dbRow {
+	return &dbRow{row: tx.Tx.QueryRow(query, args...)}
+}
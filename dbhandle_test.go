@@ -0,0 +1,300 @@
+package main
+
+import (
+	"archive/zip"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestApp opens a migrated collection in a temporary folder.
+func newTestApp(t *testing.T) *App {
+	t.Helper()
+	dir := t.TempDir()
+	a := NewApp()
+	a.profiles.root, a.profiles.mode = dir, "default"
+	db, err := a.prepareDatabase(appPathsFor(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.db = newDBHandle(db, appPathsFor(dir))
+	t.Cleanup(func() {
+		a.jobs.stop()
+		_ = a.db.Close()
+	})
+	return a
+}
+
+// addTestSet creates a location, box and set and returns the set ID.
+func addTestSet(t *testing.T, a *App, name string) int64 {
+	t.Helper()
+	loc, err := a.CreateLocation("Shelf "+name, "", "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	box, err := a.CreateBox(loc, "B-"+name, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := a.CreateBagWithSet(box, "", name, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+// hammer runs reads and writes against a until stop is closed. Every call
+// must either succeed, hit a swap in progress or find the set gone after a
+// switch; anything else is reported.
+func hammer(t *testing.T, a *App, setID int64, stop <-chan struct{}) (wait func() int64) {
+	t.Helper()
+	var wg sync.WaitGroup
+	var calls atomic.Int64
+	errs := make(chan error, 64)
+	for w := 0; w < 6; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				var err error
+				switch w % 3 {
+				case 0:
+					_, err = a.SearchSets("", "")
+				case 1:
+					_, err = a.GetSet(setID)
+				case 2:
+					var tx *dbTx
+					if tx, err = a.db.Begin(); err == nil {
+						var n int
+						err = tx.QueryRow(`SELECT COUNT(*) FROM sets`).Scan(&n)
+						_ = tx.Rollback()
+					}
+				}
+				_ = a.paths().ImagesDir
+				calls.Add(1)
+				if err != nil && !errors.Is(err, errMaintenance) && !isNoRows(err) {
+					select {
+					case errs <- err:
+					default:
+					}
+				}
+			}
+		}(w)
+	}
+	return func() int64 {
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			t.Errorf("call failed during swap: %v", err)
+		}
+		return calls.Load()
+	}
+}
+
+func isNoRows(err error) bool {
+	return err != nil && err.Error() == "sql: no rows in result set"
+}
+
+func TestSwapWaitsForOpenTransaction(t *testing.T) {
+	a := newTestApp(t)
+	tx, err := a.db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	swapped := make(chan error, 1)
+	go func() {
+		swapped <- a.db.swap(func(old *sql.DB, paths AppPaths) (*sql.DB, AppPaths, error) {
+			return old, paths, nil
+		})
+	}()
+
+	// The swap must not run while the transaction is open, and new calls
+	// are turned away instead of queueing behind it.
+	deadline := time.Now().Add(time.Second)
+	for {
+		if _, err := a.db.Exec(`SELECT 1`); errors.Is(err, errMaintenance) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("calls were not refused while a swap was waiting")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case err := <-swapped:
+		t.Fatalf("swap finished while a transaction was open: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-swapped:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("swap did not finish after the transaction ended")
+	}
+	if _, err := a.db.Exec(`SELECT 1`); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSwapWaitsForOpenRows(t *testing.T) {
+	a := newTestApp(t)
+	addTestSet(t, a, "one")
+	rows, err := a.db.Query(`SELECT id FROM sets`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	swapped := make(chan error, 1)
+	go func() {
+		swapped <- a.db.swap(func(old *sql.DB, paths AppPaths) (*sql.DB, AppPaths, error) {
+			return old, paths, nil
+		})
+	}()
+	select {
+	case <-swapped:
+		t.Fatal("swap finished while rows were open")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Reading the rows to the end releases them like Close does.
+	for rows.Next() {
+	}
+	if err := <-swapped; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClosedHandleReportsError(t *testing.T) {
+	a := newTestApp(t)
+	if err := a.db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	if err := a.db.QueryRow(`SELECT 1`).Scan(&n); !errors.Is(err, errDatabaseClosed) {
+		t.Fatalf("QueryRow on a closed handle: %v", err)
+	}
+	if _, err := a.db.Begin(); !errors.Is(err, errDatabaseClosed) {
+		t.Fatalf("Begin on a closed handle: %v", err)
+	}
+}
+
+func TestSwitchProfileWhileQuerying(t *testing.T) {
+	a := newTestApp(t)
+	setID := addTestSet(t, a, "switch")
+	other, err := a.CreateProfile("Other")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stop := make(chan struct{})
+	wait := hammer(t, a, setID, stop)
+	for i := 0; i < 20; i++ {
+		target := other.ID
+		if i%2 == 1 {
+			target = defaultProfileID
+		}
+		if err := a.SwitchProfile(target); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(stop)
+	if calls := wait(); calls == 0 {
+		t.Fatal("no calls ran during the switches")
+	}
+	if got := a.paths().BaseDir; got != a.profiles.baseDir(defaultProfileID) {
+		t.Fatalf("paths point to %s after switching back", got)
+	}
+}
+
+func TestImportWhileQuerying(t *testing.T) {
+	a := newTestApp(t)
+	setID := addTestSet(t, a, "before")
+
+	// Build a backup from a second collection holding one other set.
+	src := newTestApp(t)
+	addTestSet(t, src, "imported")
+	if err := src.db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	backup := filepath.Join(t.TempDir(), "backup.zip")
+	f, err := os.Create(backup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	if err := addFileToZip(zw, src.paths().DBPath, "Data/samla.db"); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	stop := make(chan struct{})
+	wait := hammer(t, a, setID, stop)
+	for i := 0; i < 5; i++ {
+		if err := a.importBackup(backup); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(stop)
+	wait()
+
+	results, err := a.SearchSets("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].SetName != "imported" {
+		t.Fatalf("after import: %+v", results)
+	}
+}
+
+func TestFailedTransactionsAreReleased(t *testing.T) {
+	a := newTestApp(t)
+	setID := addTestSet(t, a, "release")
+	var boxID int64
+	if err := a.db.QueryRow(`SELECT b.box_id FROM sets s JOIN bags b ON b.id = s.bag_id WHERE s.id = ?`, setID).Scan(&boxID); err != nil {
+		t.Fatal(err)
+	}
+	for _, table := range []string{"manufacturers", "types", "tags"} {
+		if _, err := a.db.Exec(`CREATE TRIGGER fail_` + table + ` BEFORE INSERT ON ` + table + ` BEGIN SELECT RAISE(ABORT, 'refused'); END`); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	calls := map[string]func() error{
+		"CreateBagWithSet manufacturer": func() error { _, err := a.CreateBagWithSet(boxID, "", "new", "Maker", ""); return err },
+		"CreateBagWithSet type":         func() error { _, err := a.CreateBagWithSet(boxID, "", "new", "", "Kind"); return err },
+		"UpdateSet manufacturer":        func() error { return a.UpdateSet(setID, "release", "Maker", "", boxID, "") },
+		"UpdateSet type":                func() error { return a.UpdateSet(setID, "release", "", "Kind", boxID, "") },
+		"SetTags":                       func() error { return a.SetTags(setID, []string{"tag"}) },
+	}
+	for name, call := range calls {
+		if err := call(); err == nil {
+			t.Fatalf("%s: expected the trigger to refuse the insert", name)
+		}
+		err := a.db.swap(func(old *sql.DB, paths AppPaths) (*sql.DB, AppPaths, error) {
+			return old, paths, nil
+		})
+		if err != nil {
+			t.Fatalf("swap after failed %s: %v", name, err)
+		}
+	}
+}
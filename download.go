@@ -0,0 +1,273 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	settingDownloadMaxBytes     = "download.max_bytes"
+	settingDownloadAllowPrivate = "download.allow_private"
+	defaultDownloadMaxBytes     = 25 << 20
+
+	downloadMaxRedirects  = 5
+	downloadTimeout       = 2 * time.Minute
+	downloadHeaderTimeout = 15 * time.Second
+	downloadUserAgent     = "Samla (+https://github.com/brendlij/Samla)"
+
+	// EventDownloadProgress is emitted while an image is downloaded.
+	EventDownloadProgress = "download:progress"
+)
+
+// DownloadSettings limits what AttachImageFromURL may fetch.
+type DownloadSettings struct {
+	MaxBytes     int64 `json:"maxBytes"`
+	AllowPrivate bool  `json:"allowPrivate"`
+}
+
+// DownloadProgress is the payload of EventDownloadProgress. Total is 0 when
+// the server does not announce the size.
+type DownloadProgress struct {
+	ID       int64  `json:"id"`
+	URL      string `json:"url"`
+	Received int64  `json:"received"`
+	Total    int64  `json:"total"`
+	Done     bool   `json:"done"`
+}
+
+// downloadRegistry tracks running downloads so they can be cancelled.
+type downloadRegistry struct {
+	mu      sync.Mutex
+	nextID  int64
+	cancels map[int64]context.CancelFunc
+}
+
+var errPrivateAddress = errors.New("downloads from local network addresses are not allowed")
+
+func readDownloadSettings(q queryer) (DownloadSettings, error) {
+	defaults := DownloadSettings{MaxBytes: defaultDownloadMaxBytes}
+	settings := defaults
+	maxBytes, err := readSetting(q, settingDownloadMaxBytes, strconv.Itoa(defaultDownloadMaxBytes))
+	if err != nil {
+		return settings, err
+	}
+	allow, err := readSetting(q, settingDownloadAllowPrivate, "false")
+	if err != nil {
+		return settings, err
+	}
+	if n, err := strconv.ParseInt(maxBytes, 10, 64); err == nil {
+		settings.MaxBytes = n
+	}
+	settings.AllowPrivate, _ = strconv.ParseBool(allow)
+	if err := validateDownloadSettings(settings); err != nil {
+		return defaults, nil
+	}
+	return settings, nil
+}
+
+func validateDownloadSettings(settings DownloadSettings) error {
+	if settings.MaxBytes < 1<<20 || settings.MaxBytes > 500<<20 {
+		return errors.New("maximum download size must be between 1 MB and 500 MB")
+	}
+	return nil
+}
+
+func (a *App) GetDownloadSettings() (DownloadSettings, error) {
+	return readDownloadSettings(a.db)
+}
+
+func writeDownloadSettings(q queryer, settings DownloadSettings) error {
+	if err := writeSetting(q, settingDownloadMaxBytes, strconv.FormatInt(settings.MaxBytes, 10)); err != nil {
+		return err
+	}
+	return writeSetting(q, settingDownloadAllowPrivate, strconv.FormatBool(settings.AllowPrivate))
+}
+
+// UpdateDownloadSettings stores the size limit and whether local network addresses may be fetched.
+func (a *App) UpdateDownloadSettings(settings DownloadSettings) error {
+	_, err := a.updateSettings(func(s *Settings) {
+		s.Download = settings
+	})
+	return err
+}
+
+// CancelDownload stops a running download by the ID from its progress events.
+// ID 0 cancels all running downloads.
+func (a *App) CancelDownload(id int64) {
+	r := &a.downloads
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for downloadID, cancel := range r.cancels {
+		if id == 0 || id == downloadID {
+			cancel()
+		}
+	}
+}
+
+func (r *downloadRegistry) start(parent context.Context) (int64, context.Context, func()) {
+	ctx, cancel := context.WithTimeout(parent, downloadTimeout)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cancels == nil {
+		r.cancels = map[int64]context.CancelFunc{}
+	}
+	r.nextID++
+	id := r.nextID
+	r.cancels[id] = cancel
+	return id, ctx, func() {
+		cancel()
+		r.mu.Lock()
+		delete(r.cancels, id)
+		r.mu.Unlock()
+	}
+}
+
+// downloadImage fetches rawURL within the configured limits and reports
+// progress to the UI.
+func (a *App) downloadImage(rawURL string) ([]byte, error) {
+	parsed, err := parseDownloadURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	settings, err := readDownloadSettings(a.db)
+	if err != nil {
+		return nil, err
+	}
+
+	parent := a.ctx
+	if parent == nil {
+		parent = context.Background()
+	}
+	id, ctx, done := a.downloads.start(parent)
+	defer done()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, parsed.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", downloadUserAgent)
+	req.Header.Set("Accept", "image/*")
+
+	resp, err := newDownloadClient(settings).Do(req)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			return nil, errors.New("download cancelled")
+		}
+		if errors.Is(err, errPrivateAddress) {
+			return nil, errPrivateAddress
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("failed to download image (status %d)", resp.StatusCode)
+	}
+	if resp.ContentLength > settings.MaxBytes {
+		return nil, fmt.Errorf("image is larger than the %d MB download limit", settings.MaxBytes>>20)
+	}
+
+	progress := DownloadProgress{ID: id, URL: parsed.String(), Total: max(resp.ContentLength, 0)}
+	body := &progressReader{r: io.LimitReader(resp.Body, settings.MaxBytes+1), emit: func(n int64) {
+		progress.Received = n
+		a.emitEvent(EventDownloadProgress, progress)
+	}}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			return nil, errors.New("download cancelled")
+		}
+		return nil, err
+	}
+	if int64(len(data)) > settings.MaxBytes {
+		return nil, fmt.Errorf("image is larger than the %d MB download limit", settings.MaxBytes>>20)
+	}
+	progress.Received = int64(len(data))
+	progress.Done = true
+	a.emitEvent(EventDownloadProgress, progress)
+	return data, nil
+}
+
+func parseDownloadURL(rawURL string) (*url.URL, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return nil, errors.New("only http and https links are supported")
+	}
+	if parsed.Hostname() == "" {
+		return nil, errors.New("url has no host")
+	}
+	return parsed, nil
+}
+
+// newDownloadClient builds a client that checks every address it connects to,
+// including redirect targets, so DNS tricks cannot reach the local network.
+func newDownloadClient(settings DownloadSettings) *http.Client {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	if !settings.AllowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || isPrivateIP(ip) {
+				return errPrivateAddress
+			}
+			return nil
+		}
+	}
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ResponseHeaderTimeout: downloadHeaderTimeout,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= downloadMaxRedirects {
+				return fmt.Errorf("stopped after %d redirects", downloadMaxRedirects)
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return errors.New("redirect to unsupported link")
+			}
+			req.Header.Set("User-Agent", downloadUserAgent)
+			return nil
+		},
+	}
+}
+
+var cgnatNet = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+func isPrivateIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() || cgnatNet.Contains(ip)
+}
+
+// progressReader reports the bytes read so far, at most every 100ms.
+type progressReader struct {
+	r    io.Reader
+	n    int64
+	last time.Time
+	emit func(int64)
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.n += int64(n)
+	if now := time.Now(); now.Sub(p.last) >= 100*time.Millisecond {
+		p.last = now
+		p.emit(p.n)
+	}
+	return n, err
+}
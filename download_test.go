@@ -0,0 +1,163 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestIsPrivateIP(t *testing.T) {
+	tests := []struct {
+		ip      string
+		private bool
+	}{
+		{"127.0.0.1", true},
+		{"127.255.255.254", true},
+		{"::1", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"172.31.255.255", true},
+		{"192.168.178.1", true},
+		{"169.254.169.254", true},
+		{"fe80::1", true},
+		{"100.64.0.1", true},
+		{"100.127.255.255", true},
+		{"fc00::1", true},
+		{"fd12:3456:789a::1", true},
+		{"::ffff:127.0.0.1", true},
+		{"::ffff:10.0.0.1", true},
+		{"::ffff:192.168.1.1", true},
+		{"::ffff:100.64.0.1", true},
+		{"0.0.0.0", true},
+		{"::", true},
+		{"224.0.0.1", true},
+		{"ff02::1", true},
+		{"8.8.8.8", false},
+		{"172.32.0.1", false},
+		{"100.63.255.255", false},
+		{"100.128.0.1", false},
+		{"2606:4700::1111", false},
+		{"::ffff:8.8.8.8", false},
+	}
+	for _, tt := range tests {
+		ip := net.ParseIP(tt.ip)
+		if ip == nil {
+			t.Fatalf("bad test address %s", tt.ip)
+		}
+		if got := isPrivateIP(ip); got != tt.private {
+			t.Errorf("isPrivateIP(%s) = %v, want %v", tt.ip, got, tt.private)
+		}
+	}
+}
+
+func TestParseDownloadURL(t *testing.T) {
+	for _, raw := range []string{"http://example.com/a.jpg", "https://example.com:8443/a.png?x=1"} {
+		if _, err := parseDownloadURL(raw); err != nil {
+			t.Errorf("%s: %v", raw, err)
+		}
+	}
+	for _, raw := range []string{"ftp://example.com/a.jpg", "file:///etc/passwd", "javascript:alert(1)", "http:///a.jpg", "://bad", "example.com/a.jpg"} {
+		if _, err := parseDownloadURL(raw); err == nil {
+			t.Errorf("%s was accepted", raw)
+		}
+	}
+}
+
+// downloadTestApp returns an app whose downloads use the given settings.
+func downloadTestApp(t *testing.T, settings DownloadSettings) *App {
+	t.Helper()
+	a := newTestApp(t)
+	if err := a.UpdateDownloadSettings(settings); err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func TestDownloadPrivateAddress(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("image"))
+	}))
+	defer srv.Close()
+
+	a := downloadTestApp(t, DownloadSettings{MaxBytes: 1 << 20})
+	if _, err := a.downloadImage(srv.URL + "/a.png"); !errors.Is(err, errPrivateAddress) {
+		t.Fatalf("loopback with private addresses off: %v", err)
+	}
+
+	a = downloadTestApp(t, DownloadSettings{MaxBytes: 1 << 20, AllowPrivate: true})
+	data, err := a.downloadImage(srv.URL + "/a.png")
+	if err != nil {
+		t.Fatalf("loopback with private addresses on: %v", err)
+	}
+	if string(data) != "image" {
+		t.Fatalf("got %q", data)
+	}
+}
+
+func TestDownloadRedirects(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ftp":
+			http.Redirect(w, r, "ftp://example.com/a.png", http.StatusFound)
+		case "/image":
+			w.Write([]byte("image"))
+		default:
+			// /hops/n redirects n more times before reaching the image.
+			var n int
+			if _, err := fmt.Sscanf(r.URL.Path, "/hops/%d", &n); err != nil || n == 0 {
+				http.Redirect(w, r, "/image", http.StatusFound)
+				return
+			}
+			http.Redirect(w, r, "/hops/"+strconv.Itoa(n-1), http.StatusFound)
+		}
+	}))
+	defer srv.Close()
+
+	a := downloadTestApp(t, DownloadSettings{MaxBytes: 1 << 20, AllowPrivate: true})
+	if _, err := a.downloadImage(srv.URL + "/hops/" + strconv.Itoa(downloadMaxRedirects-2)); err != nil {
+		t.Errorf("%d redirects: %v", downloadMaxRedirects-1, err)
+	}
+	if _, err := a.downloadImage(srv.URL + "/hops/" + strconv.Itoa(downloadMaxRedirects)); err == nil || !strings.Contains(err.Error(), "redirects") {
+		t.Errorf("%d redirects: %v", downloadMaxRedirects+1, err)
+	}
+	if _, err := a.downloadImage(srv.URL + "/ftp"); err == nil || !strings.Contains(err.Error(), "unsupported") {
+		t.Errorf("redirect to ftp: %v", err)
+	}
+}
+
+func TestDownloadSizeLimit(t *testing.T) {
+	const limit = 1 << 20
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		size := limit
+		if r.URL.Query().Get("big") != "" {
+			size++
+		}
+		if r.URL.Query().Get("chunked") != "" {
+			// Without a length the limit is only noticed while reading.
+			w.WriteHeader(http.StatusOK)
+			w.(http.Flusher).Flush()
+		} else {
+			w.Header().Set("Content-Length", strconv.Itoa(size))
+		}
+		w.Write(make([]byte, size))
+	}))
+	defer srv.Close()
+
+	a := downloadTestApp(t, DownloadSettings{MaxBytes: limit, AllowPrivate: true})
+	for _, query := range []string{"", "?chunked=1"} {
+		data, err := a.downloadImage(srv.URL + "/" + query)
+		if err != nil || len(data) != limit {
+			t.Errorf("%q at the limit: %d bytes, %v", query, len(data), err)
+		}
+	}
+	for _, query := range []string{"?big=1", "?big=1&chunked=1"} {
+		if _, err := a.downloadImage(srv.URL + "/" + query); err == nil || !strings.Contains(err.Error(), "download limit") {
+			t.Errorf("%q over the limit: %v", query, err)
+		}
+	}
+}
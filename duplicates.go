@@ -0,0 +1,252 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// DuplicateQuery describes a set that is about to be created.
+type DuplicateQuery struct {
+	Name         string   `json:"name"`
+	Manufacturer string   `json:"manufacturer"`
+	Products     []string `json:"products"`
+	Barcode      string   `json:"barcode"`
+	CatalogNo    string   `json:"catalogNo"`
+}
+
+// DuplicateCandidate is an owned set that may be the same as the queried one.
+// Score is between 0 and 1; Reasons lists what matched: "barcode", "catalog",
+// "name", "manufacturer" or "products".
+type DuplicateCandidate struct {
+	SetID            int64    `json:"setId"`
+	SetName          string   `json:"setName"`
+	ManufacturerName string   `json:"manufacturerName"`
+	BoxCode          string   `json:"boxCode"`
+	BagSerial        string   `json:"bagSerial"`
+	Score            float64  `json:"score"`
+	Reasons          []string `json:"reasons"`
+}
+
+const (
+	duplicateMinScore   = 0.55
+	duplicateMaxResults = 10
+)
+
+type ownedSet struct {
+	DuplicateCandidate
+	barcode   string
+	catalogNo string
+	products  []string
+	barcodes  []string
+}
+
+// FindDuplicateSets looks for owned sets that are likely the same as q, best match first.
+// Call it before CreateBagWithSet to warn about buying something twice.
+func (a *App) FindDuplicateSets(q DuplicateQuery) ([]DuplicateCandidate, error) {
+	name := duplicateKey(q.Name)
+	barcode := normalizeBarcode(q.Barcode)
+	catalogNo := duplicateKey(q.CatalogNo)
+	if name == "" && barcode == "" && catalogNo == "" {
+		return nil, nil
+	}
+
+	sets, err := a.loadOwnedSets()
+	if err != nil {
+		return nil, err
+	}
+
+	wantProducts := map[string]bool{}
+	for _, p := range q.Products {
+		if k := duplicateKey(p); k != "" {
+			wantProducts[k] = true
+		}
+	}
+	manufacturer := normalizeLower(q.Manufacturer)
+
+	var results []DuplicateCandidate
+	for _, s := range sets {
+		c := s.DuplicateCandidate
+		sameManufacturer := manufacturer != "" && normalizeLower(c.ManufacturerName) == manufacturer
+
+		if barcode != "" && (s.barcode == barcode || containsString(s.barcodes, barcode)) {
+			c.Score = 1
+			c.Reasons = append(c.Reasons, "barcode")
+		}
+		if catalogNo != "" && s.catalogNo == catalogNo && (manufacturer == "" || sameManufacturer) {
+			c.Score = max(c.Score, 0.95)
+			c.Reasons = append(c.Reasons, "catalog")
+		}
+
+		nameSim := 0.0
+		if name != "" {
+			nameSim = nameSimilarity(name, duplicateKey(c.SetName))
+		}
+		productSim := productOverlap(wantProducts, s.products)
+
+		// Weigh name, manufacturer and products; the latter two only count when given.
+		score, weight := 0.6*nameSim, 0.6
+		if manufacturer != "" {
+			score += 0.2 * boolScore(sameManufacturer)
+			weight += 0.2
+		}
+		if len(wantProducts) > 0 {
+			score += 0.3 * productSim
+			weight += 0.3
+		}
+		score /= weight
+		if nameSim >= 0.8 {
+			c.Reasons = append(c.Reasons, "name")
+		}
+		if sameManufacturer && nameSim > 0 {
+			c.Reasons = append(c.Reasons, "manufacturer")
+		}
+		if productSim >= 0.5 {
+			c.Reasons = append(c.Reasons, "products")
+		}
+		c.Score = max(c.Score, score)
+
+		if c.Score >= duplicateMinScore {
+			results = append(results, c)
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	if len(results) > duplicateMaxResults {
+		results = results[:duplicateMaxResults]
+	}
+	return results, nil
+}
+
+func (a *App) loadOwnedSets() ([]*ownedSet, error) {
+	rows, err := a.db.Query(`
+		SELECT s.id, s.name, IFNULL(m.name,''), bx.code, b.serial_no, IFNULL(s.barcode,''), IFNULL(s.catalog_no,'')
+		FROM sets s
+		JOIN bags b ON b.id = s.bag_id
+		JOIN boxes bx ON bx.id = b.box_id
+		LEFT JOIN manufacturers m ON m.id = s.manufacturer_id
+		WHERE s.deleted_at IS NULL`)
+	if err != nil {
+		return nil, err
+	}
+	var sets []*ownedSet
+	byID := map[int64]*ownedSet{}
+	for rows.Next() {
+		s := &ownedSet{}
+		if err := rows.Scan(&s.SetID, &s.SetName, &s.ManufacturerName, &s.BoxCode, &s.BagSerial, &s.barcode, &s.catalogNo); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		s.catalogNo = duplicateKey(s.catalogNo)
+		sets = append(sets, s)
+		byID[s.SetID] = s
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	eRows, err := a.db.Query(`SELECT set_id, name, IFNULL(barcode,'') FROM elements`)
+	if err != nil {
+		return nil, err
+	}
+	defer eRows.Close()
+	for eRows.Next() {
+		var setID int64
+		var name, barcode string
+		if err := eRows.Scan(&setID, &name, &barcode); err != nil {
+			return nil, err
+		}
+		s, ok := byID[setID]
+		if !ok {
+			continue
+		}
+		if k := duplicateKey(name); k != "" {
+			s.products = append(s.products, k)
+		}
+		if barcode != "" {
+			s.barcodes = append(s.barcodes, barcode)
+		}
+	}
+	return sets, eRows.Err()
+}
+
+// duplicateKey lowercases a name and reduces punctuation and spacing to single spaces.
+func duplicateKey(val string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(val), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+// nameSimilarity combines edit distance and shared words so that both typos
+// ("Rose Gardn") and reordered names ("Garden Rose") score high.
+func nameSimilarity(a, b string) float64 {
+	if a == "" || b == "" {
+		return 0
+	}
+	if a == b {
+		return 1
+	}
+	ra, rb := []rune(a), []rune(b)
+	edit := 1 - float64(levenshtein(ra, rb))/float64(max(len(ra), len(rb)))
+
+	wa, wb := strings.Fields(a), strings.Fields(b)
+	shared := 0
+	for _, w := range wa {
+		if containsString(wb, w) {
+			shared++
+		}
+	}
+	words := float64(shared) / float64(len(wa)+len(wb)-shared)
+	return max(edit, words)
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// productOverlap is the share of wanted product names found in the set.
+func productOverlap(want map[string]bool, have []string) float64 {
+	if len(want) == 0 || len(have) == 0 {
+		return 0
+	}
+	found := map[string]bool{}
+	for _, p := range have {
+		if want[p] {
+			found[p] = true
+		}
+	}
+	return float64(len(found)) / float64(len(want))
+}
+
+func boolScore(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func containsString(list []string, val string) bool {
+	for _, s := range list {
+		if s == val {
+			return true
+		}
+	}
+	return false
+}
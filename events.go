@@ -0,0 +1,85 @@
+package main
+
+import "github.com/wailsapp/wails/v2/pkg/runtime"
+
+// Change events tell every window which data changed, so it can refresh
+// what it shows instead of reloading everything. Entity events carry a
+// ChangeEvent with the IDs of the affected rows.
+const (
+	EventSetCreated  = "set:created"
+	EventSetUpdated  = "set:updated"
+	EventSetDeleted  = "set:deleted" // moved to the trash
+	EventSetRestored = "set:restored"
+	EventSetPurged   = "set:purged" // removed from the trash for good
+
+	EventBoxCreated = "box:created"
+	EventBoxUpdated = "box:updated"
+	EventBoxDeleted = "box:deleted"
+
+	EventLocationCreated = "location:created"
+	EventLocationUpdated = "location:updated"
+	EventLocationDeleted = "location:deleted"
+
+	// EventImageAttached and EventImageRemoved carry an ImageEvent.
+	EventImageAttached = "image:attached"
+	EventImageRemoved  = "image:removed"
+
+	// EventImportCompleted carries an ImportEvent once a backup was restored.
+	EventImportCompleted = "import:completed"
+	// EventHistoryApplied is emitted after Undo or Redo with the description
+	// of the step. Any data may have changed, so listeners reload.
+	EventHistoryApplied = "history:applied"
+)
+
+// ChangeEvent is the payload of the entity events.
+type ChangeEvent struct {
+	IDs []int64 `json:"ids"`
+}
+
+// ImageEvent names the set whose image changed and its new path, empty when removed.
+type ImageEvent struct {
+	SetID int64  `json:"setId"`
+	Path  string `json:"path"`
+}
+
+type ImportEvent struct {
+	Path string `json:"path"`
+}
+
+// emitEvent sends an event to the UI; it is a no-op before startup.
+func (a *App) emitEvent(name string, data ...interface{}) {
+	if a.ctx != nil {
+		runtime.EventsEmit(a.ctx, name, data...)
+	}
+}
+
+// emitChange sends an entity event for the given IDs; nothing is sent without IDs.
+func (a *App) emitChange(name string, ids ...int64) {
+	if len(ids) > 0 {
+		a.emitEvent(name, ChangeEvent{IDs: ids})
+	}
+}
+
+// pendingChange is an entity event collected inside a transaction and sent
+// once it has committed.
+type pendingChange struct {
+	name string
+	ids  []int64
+}
+
+func (a *App) emitChanges(changes []pendingChange) {
+	for _, c := range changes {
+		a.emitChange(c.name, c.ids...)
+	}
+}
+
+// changedSets returns the sets a bulk operation changed successfully.
+func changedSets(results []BulkResult) []int64 {
+	var ids []int64
+	for _, r := range results {
+		if r.OK {
+			ids = append(ids, r.SetID)
+		}
+	}
+	return ids
+}
@@ -2,16 +2,93 @@ package main
 
 import (
 	"archive/zip"
+	"database/sql"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
+const (
+	settingBackupInterval = "backup.interval"
+	settingBackupFolder   = "backup.folder"
+	settingBackupKeep     = "backup.keep"
+
+	defaultBackupKeep = 5
+)
+
+// BackupSettings is the schedule for automatic backups: Interval is "off",
+// "daily", "weekly" or "monthly", Folder is where the zip files are written
+// and Keep is how many of them to keep, 0 for all. Only the schedule is stored
+// here; creating backups on it is not part of the settings store.
+type BackupSettings struct {
+	Interval string `json:"interval"`
+	Folder   string `json:"folder"`
+	Keep     int    `json:"keep"`
+}
+
+func readBackupSettings(q queryer) (BackupSettings, error) {
+	defaults := BackupSettings{Interval: "off", Keep: defaultBackupKeep}
+	settings := defaults
+	interval, err := readSetting(q, settingBackupInterval, "off")
+	if err != nil {
+		return settings, err
+	}
+	folder, err := readSetting(q, settingBackupFolder, "")
+	if err != nil {
+		return settings, err
+	}
+	keep, err := readSetting(q, settingBackupKeep, strconv.Itoa(defaultBackupKeep))
+	if err != nil {
+		return settings, err
+	}
+	settings.Interval, settings.Folder = interval, folder
+	if n, err := strconv.Atoi(keep); err == nil {
+		settings.Keep = n
+	}
+	if err := validateBackupSettings(&settings); err != nil {
+		return defaults, nil
+	}
+	return settings, nil
+}
+
+func validateBackupSettings(settings *BackupSettings) error {
+	settings.Folder = strings.TrimSpace(settings.Folder)
+	switch settings.Interval {
+	case "":
+		settings.Interval = "off"
+	case "off", "daily", "weekly", "monthly":
+	default:
+		return fmt.Errorf("unknown backup interval %q", settings.Interval)
+	}
+	if settings.Interval != "off" && settings.Folder == "" {
+		return errors.New("please choose a folder for automatic backups")
+	}
+	if settings.Folder != "" && !filepath.IsAbs(settings.Folder) {
+		return errors.New("the backup folder must be a full path")
+	}
+	if settings.Keep < 0 {
+		return errors.New("number of backups to keep cannot be negative")
+	}
+	return nil
+}
+
+func writeBackupSettings(q queryer, settings BackupSettings) error {
+	if err := writeSetting(q, settingBackupInterval, settings.Interval); err != nil {
+		return err
+	}
+	if err := writeSetting(q, settingBackupFolder, settings.Folder); err != nil {
+		return err
+	}
+	return writeSetting(q, settingBackupKeep, strconv.Itoa(settings.Keep))
+}
+
 // ExportData exports the database and images folder to a zip file
 func (a *App) ExportData() (string, error) {
 	// Open save dialog
@@ -46,7 +123,8 @@ func (a *App) ExportData() (string, error) {
 	defer zipWriter.Close()
 
 	// Add database file
-	dbPath := a.paths.DBPath
+	paths := a.paths()
+	dbPath := paths.DBPath
 	if _, err := os.Stat(dbPath); err == nil {
 		if err := addFileToZip(zipWriter, dbPath, "Data/samla.db"); err != nil {
 			return "", fmt.Errorf("failed to add database to zip: %w", err)
@@ -54,7 +132,7 @@ func (a *App) ExportData() (string, error) {
 	}
 
 	// Add images folder
-	imagesDir := a.paths.ImagesDir
+	imagesDir := paths.ImagesDir
 	if _, err := os.Stat(imagesDir); err == nil {
 		err = filepath.Walk(imagesDir, func(path string, info os.FileInfo, err error) error {
 			if err != nil {
@@ -64,7 +142,7 @@ func (a *App) ExportData() (string, error) {
 				return nil
 			}
 
-			relPath, err := filepath.Rel(a.paths.BaseDir, path)
+			relPath, err := filepath.Rel(paths.BaseDir, path)
 			if err != nil {
 				return err
 			}
@@ -96,26 +174,47 @@ func (a *App) ImportData() (string, error) {
 	if openPath == "" {
 		return "", nil // User cancelled
 	}
-
-	// Close current database connection
-	if a.db != nil {
-		a.db.Close()
-		a.db = nil
+	if err := a.importBackup(openPath); err != nil {
+		return "", err
 	}
+	return openPath, nil
+}
 
+// importBackup replaces the open collection with the backup in openPath.
+func (a *App) importBackup(openPath string) error {
 	// Open zip file
 	zipReader, err := zip.OpenReader(openPath)
 	if err != nil {
-		return "", fmt.Errorf("failed to open zip file: %w", err)
+		return fmt.Errorf("failed to open zip file: %w", err)
 	}
 	defer zipReader.Close()
 
-	// Extract files
+	// Replace the database while no other call can use it. If extracting
+	// fails, whatever is on disk is reopened.
+	err = a.replaceCollection(func(paths AppPaths) (*sql.DB, AppPaths, error) {
+		if err := extractBackup(&zipReader.Reader, paths.BaseDir); err != nil {
+			db, _ := a.prepareDatabase(paths)
+			return db, paths, err
+		}
+		// Backups from older versions may predate the current schema.
+		db, err := a.prepareDatabase(paths)
+		return db, paths, err
+	})
+	if err != nil {
+		return err
+	}
+
+	a.emitEvent(EventImportCompleted, ImportEvent{Path: openPath})
+	return nil
+}
+
+// extractBackup writes the files of a backup into baseDir.
+func extractBackup(zipReader *zip.Reader, baseDir string) error {
 	for _, file := range zipReader.File {
-		destPath := filepath.Join(a.paths.BaseDir, file.Name)
+		destPath := filepath.Join(baseDir, file.Name)
 
 		// Security check: ensure we don't write outside base dir
-		if !strings.HasPrefix(destPath, a.paths.BaseDir) {
+		if !strings.HasPrefix(destPath, baseDir) {
 			continue
 		}
 
@@ -126,23 +225,15 @@ func (a *App) ImportData() (string, error) {
 
 		// Ensure parent directory exists
 		if err := os.MkdirAll(filepath.Dir(destPath), 0o755); err != nil {
-			return "", err
+			return err
 		}
 
 		// Extract file
 		if err := extractFileFromZip(file, destPath); err != nil {
-			return "", fmt.Errorf("failed to extract %s: %w", file.Name, err)
+			return fmt.Errorf("failed to extract %s: %w", file.Name, err)
 		}
 	}
-
-	// Reopen database
-	db, err := openDatabase(a.paths.DBPath)
-	if err != nil {
-		return "", fmt.Errorf("failed to reopen database: %w", err)
-	}
-	a.db = db
-
-	return openPath, nil
+	return nil
 }
 
 // GetStats returns statistics about the data
@@ -151,16 +242,22 @@ func (a *App) GetStats() (map[string]int, error) {
 
 	// Count sets
 	var setCount int
-	if err := a.db.QueryRow(`SELECT COUNT(*) FROM sets`).Scan(&setCount); err == nil {
+	if err := a.db.QueryRow(`SELECT COUNT(*) FROM sets WHERE deleted_at IS NULL`).Scan(&setCount); err == nil {
 		stats["sets"] = setCount
 	}
 
 	// Count products
 	var productCount int
-	if err := a.db.QueryRow(`SELECT COUNT(*) FROM elements`).Scan(&productCount); err == nil {
+	if err := a.db.QueryRow(`SELECT COUNT(*) FROM elements e JOIN sets s ON s.id = e.set_id WHERE s.deleted_at IS NULL`).Scan(&productCount); err == nil {
 		stats["products"] = productCount
 	}
 
+	// Count sets in the trash
+	var trashCount int
+	if err := a.db.QueryRow(`SELECT COUNT(*) FROM sets WHERE deleted_at IS NOT NULL`).Scan(&trashCount); err == nil {
+		stats["trash"] = trashCount
+	}
+
 	// Count boxes
 	var boxCount int
 	if err := a.db.QueryRow(`SELECT COUNT(*) FROM boxes`).Scan(&boxCount); err == nil {
@@ -179,15 +276,11 @@ func (a *App) GetStats() (map[string]int, error) {
 		stats["tags"] = tagCount
 	}
 
-	// Count images
-	imageCount := 0
-	filepath.Walk(a.paths.ImagesDir, func(path string, info os.FileInfo, err error) error {
-		if err == nil && !info.IsDir() {
-			imageCount++
-		}
-		return nil
-	})
-	stats["images"] = imageCount
+	// Count images in use; identical photos share one file
+	var imageCount int
+	if err := a.db.QueryRow(`SELECT COUNT(DISTINCT photo_path) FROM sets WHERE photo_path IS NOT NULL AND photo_path != ''`).Scan(&imageCount); err == nil {
+		stats["images"] = imageCount
+	}
 
 	return stats, nil
 }
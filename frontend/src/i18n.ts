@@ -98,6 +98,14 @@ export const translations = {
     confirmImport: "Import bestätigen",
     confirmImportMessage:
       "Beim Import werden alle bestehenden Daten überschrieben. Fortfahren?",
+    deleteContentsTitle: "Inhalt verschieben oder löschen",
+    deleteLocationImpact:
+      "Dieser Standort enthält {boxes} Boxen mit {sets} Sets und {images} Bildern.",
+    deleteBoxImpact:
+      "Diese Box enthält {bags} Beutel mit {sets} Sets und {images} Bildern.",
+    moveContentsTo: "Inhalt verschieben nach",
+    moveAndDelete: "Verschieben und löschen",
+    deleteEverything: "Alles löschen",
 
     // Settings Panel
     settingsTitle: "Einstellungen",
@@ -223,6 +231,14 @@ export const translations = {
     confirmImport: "Confirm Import",
     confirmImportMessage:
       "Importing will overwrite all existing data. Continue?",
+    deleteContentsTitle: "Move or Delete Contents",
+    deleteLocationImpact:
+      "This location holds {boxes} boxes with {sets} sets and {images} images.",
+    deleteBoxImpact:
+      "This box holds {bags} bags with {sets} sets and {images} images.",
+    moveContentsTo: "Move contents to",
+    moveAndDelete: "Move and delete",
+    deleteEverything: "Delete everything",
 
     // Settings Panel
     settingsTitle: "Settings",
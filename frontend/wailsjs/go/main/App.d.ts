@@ -10,8 +10,30 @@ export function AttachImageFromURL(arg1:number,arg2:string):Promise<string>;
 
 export function AttachScannedImage(arg1:number,arg2:string):Promise<string>;
 
+export function BackfillImageHashes():Promise<number>;
+
+export function BulkAddTags(arg1:Array<number>,arg2:Array<string>):Promise<Array<main.BulkResult>>;
+
+export function BulkDeleteSets(arg1:Array<number>):Promise<Array<main.BulkResult>>;
+
+export function BulkMoveSets(arg1:Array<number>,arg2:number):Promise<Array<main.BulkResult>>;
+
+export function BulkRemoveTags(arg1:Array<number>,arg2:Array<string>):Promise<Array<main.BulkResult>>;
+
+export function BulkSetManufacturer(arg1:Array<number>,arg2:string):Promise<Array<main.BulkResult>>;
+
+export function BulkSetType(arg1:Array<number>,arg2:string):Promise<Array<main.BulkResult>>;
+
+export function CancelDownload(arg1:number):Promise<void>;
+
+export function CheckImages():Promise<main.ImageReport>;
+
+export function ChooseDataFolder():Promise<string>;
+
 export function ChooseImageFile():Promise<string>;
 
+export function CleanupImages(arg1:string,arg2:boolean):Promise<main.ImageCleanupResult>;
+
 export function CreateBagWithSet(arg1:number,arg2:string,arg3:string,arg4:string,arg5:string):Promise<number>;
 
 export function CreateBox(arg1:number,arg2:string,arg3:string):Promise<number>;
@@ -22,6 +44,8 @@ export function CreateManufacturer(arg1:string):Promise<number>;
 
 export function CreateManufacturerIfMissing(arg1:string):Promise<number>;
 
+export function CreateProfile(arg1:string):Promise<main.Profile>;
+
 export function CreateTag(arg1:string):Promise<number>;
 
 export function CreateTagIfMissing(arg1:string):Promise<number>;
@@ -30,76 +54,166 @@ export function CreateType(arg1:string):Promise<number>;
 
 export function CreateTypeIfMissing(arg1:string):Promise<number>;
 
-export function DeleteBox(arg1:number):Promise<void>;
+export function DeleteBox(arg1:number,arg2:number,arg3:boolean):Promise<void>;
 
-export function DeleteLocation(arg1:number):Promise<void>;
+export function DeleteLocation(arg1:number,arg2:number,arg3:boolean):Promise<void>;
 
 export function DeleteManufacturer(arg1:number):Promise<void>;
 
 export function DeleteProduct(arg1:number):Promise<void>;
 
+export function DeleteProfile(arg1:string):Promise<void>;
+
 export function DeleteSet(arg1:number):Promise<void>;
 
 export function DeleteTag(arg1:number):Promise<void>;
 
 export function DeleteType(arg1:number):Promise<void>;
 
+export function EmptyTrash():Promise<number>;
+
 export function ExportData():Promise<string>;
 
+export function ExportLabels(arg1:main.LabelRequest):Promise<string>;
+
+export function FindDuplicateSets(arg1:main.DuplicateQuery):Promise<Array<main.DuplicateCandidate>>;
+
 export function GetAppPaths():Promise<main.AppPaths>;
 
+export function GetBoxContents(arg1:number):Promise<main.BoxContents>;
+
+export function GetDataLocation():Promise<main.DataLocation>;
+
+export function GetDownloadSettings():Promise<main.DownloadSettings>;
+
 export function GetImageAsBase64(arg1:string):Promise<string>;
 
+export function GetImageSettings():Promise<main.ImageSettings>;
+
 export function GetNextBagSerial(arg1:number):Promise<string>;
 
+export function GetScanSettings():Promise<main.ScanSettings>;
+
+export function GetSerialSettings():Promise<main.SerialSettings>;
+
 export function GetSet(arg1:number):Promise<main.SetDetails>;
 
+export function GetSettings():Promise<main.Settings>;
+
 export function GetStats():Promise<Record<string, number>>;
 
+export function GetTrashRetentionDays():Promise<number>;
+
+export function GetUndoState():Promise<main.UndoState>;
+
 export function ImportData():Promise<string>;
 
 export function ListBoxes(arg1:number):Promise<Array<main.Box>>;
 
+export function ListLabelLayouts():Promise<Array<main.LabelLayout>>;
+
 export function ListLocations():Promise<Array<main.StorageLocation>>;
 
 export function ListManufacturers():Promise<Array<main.Manufacturer>>;
 
 export function ListProductsBySet(arg1:number):Promise<Array<main.Product>>;
 
+export function ListProfiles():Promise<Array<main.Profile>>;
+
+export function ListScanners():Promise<Array<main.ScannerDevice>>;
+
 export function ListTags():Promise<Array<string>>;
 
 export function ListTagsFull():Promise<Array<main.Tag>>;
 
+export function ListTrash():Promise<Array<main.TrashedSet>>;
+
 export function ListTypes():Promise<Array<main.Type>>;
 
+export function LookupByImage(arg1:string):Promise<main.LookupResult>;
+
+export function LookupCode(arg1:string):Promise<main.LookupResult>;
+
+export function MoveBox(arg1:number,arg2:number):Promise<void>;
+
+export function MoveDataFolder(arg1:string):Promise<main.DataLocation>;
+
+export function NormalizeImages():Promise<main.NormalizeResult>;
+
 export function OpenAppFolder():Promise<void>;
 
+export function PreviewDeleteBox(arg1:number):Promise<main.DeleteImpact>;
+
+export function PreviewDeleteLocation(arg1:number):Promise<main.DeleteImpact>;
+
+export function PreviewSerialTemplate(arg1:string,arg2:string):Promise<Array<string>>;
+
+export function PurgeSet(arg1:number):Promise<void>;
+
 export function ReadFileAsBase64(arg1:string):Promise<string>;
 
+export function Redo():Promise<string>;
+
 export function RemoveImage(arg1:number):Promise<void>;
 
+export function RenameProfile(arg1:string,arg2:string):Promise<void>;
+
+export function RenderLabelsSVG(arg1:main.LabelRequest):Promise<Array<string>>;
+
+export function RenumberBags(arg1:number,arg2:string):Promise<Array<main.SerialChange>>;
+
 export function ResolveImagePath(arg1:string):Promise<string>;
 
+export function RestoreSet(arg1:number):Promise<void>;
+
 export function SaveCroppedImage(arg1:number,arg2:string,arg3:string):Promise<string>;
 
 export function ScanImage():Promise<string>;
 
 export function ScanImageToBase64():Promise<main.ScanResult>;
 
+export function ScanPages(arg1:main.ScanOptions):Promise<Array<string>>;
+
+export function SearchByImage(arg1:string):Promise<Array<main.SimilarSet>>;
+
 export function SearchSets(arg1:string,arg2:string):Promise<Array<main.SetSearchResult>>;
 
+export function SetBoxSerialTemplate(arg1:number,arg2:string):Promise<void>;
+
 export function SetTags(arg1:number,arg2:Array<string>):Promise<void>;
 
+export function SetTrashRetentionDays(arg1:number):Promise<void>;
+
+export function SwitchProfile(arg1:string):Promise<void>;
+
+export function Undo():Promise<string>;
+
 export function UpdateBox(arg1:number,arg2:number,arg3:string,arg4:string):Promise<void>;
 
+export function UpdateDownloadSettings(arg1:main.DownloadSettings):Promise<void>;
+
+export function UpdateImageSettings(arg1:main.ImageSettings):Promise<void>;
+
 export function UpdateLocation(arg1:number,arg2:string,arg3:string,arg4:string,arg5:string,arg6:string):Promise<void>;
 
 export function UpdateManufacturer(arg1:number,arg2:string):Promise<void>;
 
 export function UpdateProduct(arg1:number,arg2:string,arg3:string):Promise<void>;
 
+export function UpdateProductBarcode(arg1:number,arg2:string):Promise<Array<main.BarcodeConflict>>;
+
+export function UpdateScanSettings(arg1:main.ScanSettings):Promise<void>;
+
+export function UpdateSerialSettings(arg1:main.SerialSettings):Promise<void>;
+
 export function UpdateSet(arg1:number,arg2:string,arg3:string,arg4:string,arg5:number,arg6:string):Promise<void>;
 
+export function UpdateSetBarcode(arg1:number,arg2:string):Promise<Array<main.BarcodeConflict>>;
+
+export function UpdateSetCatalogNumber(arg1:number,arg2:string):Promise<void>;
+
+export function UpdateSettings(arg1:main.Settings):Promise<main.Settings>;
+
 export function UpdateTag(arg1:number,arg2:string):Promise<void>;
 
 export function UpdateType(arg1:number,arg2:string):Promise<void>;
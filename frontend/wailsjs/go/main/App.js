@@ -18,10 +18,54 @@ export function AttachScannedImage(arg1, arg2) {
   return window['go']['main']['App']['AttachScannedImage'](arg1, arg2);
 }
 
+export function BackfillImageHashes() {
+  return window['go']['main']['App']['BackfillImageHashes']();
+}
+
+export function BulkAddTags(arg1, arg2) {
+  return window['go']['main']['App']['BulkAddTags'](arg1, arg2);
+}
+
+export function BulkDeleteSets(arg1) {
+  return window['go']['main']['App']['BulkDeleteSets'](arg1);
+}
+
+export function BulkMoveSets(arg1, arg2) {
+  return window['go']['main']['App']['BulkMoveSets'](arg1, arg2);
+}
+
+export function BulkRemoveTags(arg1, arg2) {
+  return window['go']['main']['App']['BulkRemoveTags'](arg1, arg2);
+}
+
+export function BulkSetManufacturer(arg1, arg2) {
+  return window['go']['main']['App']['BulkSetManufacturer'](arg1, arg2);
+}
+
+export function BulkSetType(arg1, arg2) {
+  return window['go']['main']['App']['BulkSetType'](arg1, arg2);
+}
+
+export function CancelDownload(arg1) {
+  return window['go']['main']['App']['CancelDownload'](arg1);
+}
+
+export function CheckImages() {
+  return window['go']['main']['App']['CheckImages']();
+}
+
+export function ChooseDataFolder() {
+  return window['go']['main']['App']['ChooseDataFolder']();
+}
+
 export function ChooseImageFile() {
   return window['go']['main']['App']['ChooseImageFile']();
 }
 
+export function CleanupImages(arg1, arg2) {
+  return window['go']['main']['App']['CleanupImages'](arg1, arg2);
+}
+
 export function CreateBagWithSet(arg1, arg2, arg3, arg4, arg5) {
   return window['go']['main']['App']['CreateBagWithSet'](arg1, arg2, arg3, arg4, arg5);
 }
@@ -42,6 +86,10 @@ export function CreateManufacturerIfMissing(arg1) {
   return window['go']['main']['App']['CreateManufacturerIfMissing'](arg1);
 }
 
+export function CreateProfile(arg1) {
+  return window['go']['main']['App']['CreateProfile'](arg1);
+}
+
 export function CreateTag(arg1) {
   return window['go']['main']['App']['CreateTag'](arg1);
 }
@@ -58,12 +106,12 @@ export function CreateTypeIfMissing(arg1) {
   return window['go']['main']['App']['CreateTypeIfMissing'](arg1);
 }
 
-export function DeleteBox(arg1) {
-  return window['go']['main']['App']['DeleteBox'](arg1);
+export function DeleteBox(arg1, arg2, arg3) {
+  return window['go']['main']['App']['DeleteBox'](arg1, arg2, arg3);
 }
 
-export function DeleteLocation(arg1) {
-  return window['go']['main']['App']['DeleteLocation'](arg1);
+export function DeleteLocation(arg1, arg2, arg3) {
+  return window['go']['main']['App']['DeleteLocation'](arg1, arg2, arg3);
 }
 
 export function DeleteManufacturer(arg1) {
@@ -74,6 +122,10 @@ export function DeleteProduct(arg1) {
   return window['go']['main']['App']['DeleteProduct'](arg1);
 }
 
+export function DeleteProfile(arg1) {
+  return window['go']['main']['App']['DeleteProfile'](arg1);
+}
+
 export function DeleteSet(arg1) {
   return window['go']['main']['App']['DeleteSet'](arg1);
 }
@@ -86,30 +138,78 @@ export function DeleteType(arg1) {
   return window['go']['main']['App']['DeleteType'](arg1);
 }
 
+export function EmptyTrash() {
+  return window['go']['main']['App']['EmptyTrash']();
+}
+
 export function ExportData() {
   return window['go']['main']['App']['ExportData']();
 }
 
+export function ExportLabels(arg1) {
+  return window['go']['main']['App']['ExportLabels'](arg1);
+}
+
+export function FindDuplicateSets(arg1) {
+  return window['go']['main']['App']['FindDuplicateSets'](arg1);
+}
+
 export function GetAppPaths() {
   return window['go']['main']['App']['GetAppPaths']();
 }
 
+export function GetBoxContents(arg1) {
+  return window['go']['main']['App']['GetBoxContents'](arg1);
+}
+
+export function GetDataLocation() {
+  return window['go']['main']['App']['GetDataLocation']();
+}
+
+export function GetDownloadSettings() {
+  return window['go']['main']['App']['GetDownloadSettings']();
+}
+
 export function GetImageAsBase64(arg1) {
   return window['go']['main']['App']['GetImageAsBase64'](arg1);
 }
 
+export function GetImageSettings() {
+  return window['go']['main']['App']['GetImageSettings']();
+}
+
 export function GetNextBagSerial(arg1) {
   return window['go']['main']['App']['GetNextBagSerial'](arg1);
 }
 
+export function GetScanSettings() {
+  return window['go']['main']['App']['GetScanSettings']();
+}
+
+export function GetSerialSettings() {
+  return window['go']['main']['App']['GetSerialSettings']();
+}
+
 export function GetSet(arg1) {
   return window['go']['main']['App']['GetSet'](arg1);
 }
 
+export function GetSettings() {
+  return window['go']['main']['App']['GetSettings']();
+}
+
 export function GetStats() {
   return window['go']['main']['App']['GetStats']();
 }
 
+export function GetTrashRetentionDays() {
+  return window['go']['main']['App']['GetTrashRetentionDays']();
+}
+
+export function GetUndoState() {
+  return window['go']['main']['App']['GetUndoState']();
+}
+
 export function ImportData() {
   return window['go']['main']['App']['ImportData']();
 }
@@ -118,6 +218,10 @@ export function ListBoxes(arg1) {
   return window['go']['main']['App']['ListBoxes'](arg1);
 }
 
+export function ListLabelLayouts() {
+  return window['go']['main']['App']['ListLabelLayouts']();
+}
+
 export function ListLocations() {
   return window['go']['main']['App']['ListLocations']();
 }
@@ -130,6 +234,14 @@ export function ListProductsBySet(arg1) {
   return window['go']['main']['App']['ListProductsBySet'](arg1);
 }
 
+export function ListProfiles() {
+  return window['go']['main']['App']['ListProfiles']();
+}
+
+export function ListScanners() {
+  return window['go']['main']['App']['ListScanners']();
+}
+
 export function ListTags() {
   return window['go']['main']['App']['ListTags']();
 }
@@ -138,26 +250,86 @@ export function ListTagsFull() {
   return window['go']['main']['App']['ListTagsFull']();
 }
 
+export function ListTrash() {
+  return window['go']['main']['App']['ListTrash']();
+}
+
 export function ListTypes() {
   return window['go']['main']['App']['ListTypes']();
 }
 
+export function LookupByImage(arg1) {
+  return window['go']['main']['App']['LookupByImage'](arg1);
+}
+
+export function LookupCode(arg1) {
+  return window['go']['main']['App']['LookupCode'](arg1);
+}
+
+export function MoveBox(arg1, arg2) {
+  return window['go']['main']['App']['MoveBox'](arg1, arg2);
+}
+
+export function MoveDataFolder(arg1) {
+  return window['go']['main']['App']['MoveDataFolder'](arg1);
+}
+
+export function NormalizeImages() {
+  return window['go']['main']['App']['NormalizeImages']();
+}
+
 export function OpenAppFolder() {
   return window['go']['main']['App']['OpenAppFolder']();
 }
 
+export function PreviewDeleteBox(arg1) {
+  return window['go']['main']['App']['PreviewDeleteBox'](arg1);
+}
+
+export function PreviewDeleteLocation(arg1) {
+  return window['go']['main']['App']['PreviewDeleteLocation'](arg1);
+}
+
+export function PreviewSerialTemplate(arg1, arg2) {
+  return window['go']['main']['App']['PreviewSerialTemplate'](arg1, arg2);
+}
+
+export function PurgeSet(arg1) {
+  return window['go']['main']['App']['PurgeSet'](arg1);
+}
+
 export function ReadFileAsBase64(arg1) {
   return window['go']['main']['App']['ReadFileAsBase64'](arg1);
 }
 
+export function Redo() {
+  return window['go']['main']['App']['Redo']();
+}
+
 export function RemoveImage(arg1) {
   return window['go']['main']['App']['RemoveImage'](arg1);
 }
 
+export function RenameProfile(arg1, arg2) {
+  return window['go']['main']['App']['RenameProfile'](arg1, arg2);
+}
+
+export function RenderLabelsSVG(arg1) {
+  return window['go']['main']['App']['RenderLabelsSVG'](arg1);
+}
+
+export function RenumberBags(arg1, arg2) {
+  return window['go']['main']['App']['RenumberBags'](arg1, arg2);
+}
+
 export function ResolveImagePath(arg1) {
   return window['go']['main']['App']['ResolveImagePath'](arg1);
 }
 
+export function RestoreSet(arg1) {
+  return window['go']['main']['App']['RestoreSet'](arg1);
+}
+
 export function SaveCroppedImage(arg1, arg2, arg3) {
   return window['go']['main']['App']['SaveCroppedImage'](arg1, arg2, arg3);
 }
@@ -170,18 +342,50 @@ export function ScanImageToBase64() {
   return window['go']['main']['App']['ScanImageToBase64']();
 }
 
+export function ScanPages(arg1) {
+  return window['go']['main']['App']['ScanPages'](arg1);
+}
+
+export function SearchByImage(arg1) {
+  return window['go']['main']['App']['SearchByImage'](arg1);
+}
+
 export function SearchSets(arg1, arg2) {
   return window['go']['main']['App']['SearchSets'](arg1, arg2);
 }
 
+export function SetBoxSerialTemplate(arg1, arg2) {
+  return window['go']['main']['App']['SetBoxSerialTemplate'](arg1, arg2);
+}
+
 export function SetTags(arg1, arg2) {
   return window['go']['main']['App']['SetTags'](arg1, arg2);
 }
 
+export function SetTrashRetentionDays(arg1) {
+  return window['go']['main']['App']['SetTrashRetentionDays'](arg1);
+}
+
+export function SwitchProfile(arg1) {
+  return window['go']['main']['App']['SwitchProfile'](arg1);
+}
+
+export function Undo() {
+  return window['go']['main']['App']['Undo']();
+}
+
 export function UpdateBox(arg1, arg2, arg3, arg4) {
   return window['go']['main']['App']['UpdateBox'](arg1, arg2, arg3, arg4);
 }
 
+export function UpdateDownloadSettings(arg1) {
+  return window['go']['main']['App']['UpdateDownloadSettings'](arg1);
+}
+
+export function UpdateImageSettings(arg1) {
+  return window['go']['main']['App']['UpdateImageSettings'](arg1);
+}
+
 export function UpdateLocation(arg1, arg2, arg3, arg4, arg5, arg6) {
   return window['go']['main']['App']['UpdateLocation'](arg1, arg2, arg3, arg4, arg5, arg6);
 }
@@ -194,10 +398,34 @@ export function UpdateProduct(arg1, arg2, arg3) {
   return window['go']['main']['App']['UpdateProduct'](arg1, arg2, arg3);
 }
 
+export function UpdateProductBarcode(arg1, arg2) {
+  return window['go']['main']['App']['UpdateProductBarcode'](arg1, arg2);
+}
+
+export function UpdateScanSettings(arg1) {
+  return window['go']['main']['App']['UpdateScanSettings'](arg1);
+}
+
+export function UpdateSerialSettings(arg1) {
+  return window['go']['main']['App']['UpdateSerialSettings'](arg1);
+}
+
 export function UpdateSet(arg1, arg2, arg3, arg4, arg5, arg6) {
   return window['go']['main']['App']['UpdateSet'](arg1, arg2, arg3, arg4, arg5, arg6);
 }
 
+export function UpdateSetBarcode(arg1, arg2) {
+  return window['go']['main']['App']['UpdateSetBarcode'](arg1, arg2);
+}
+
+export function UpdateSetCatalogNumber(arg1, arg2) {
+  return window['go']['main']['App']['UpdateSetCatalogNumber'](arg1, arg2);
+}
+
+export function UpdateSettings(arg1) {
+  return window['go']['main']['App']['UpdateSettings'](arg1);
+}
+
 export function UpdateTag(arg1, arg2) {
   return window['go']['main']['App']['UpdateTag'](arg1, arg2);
 }
@@ -4,6 +4,9 @@ export namespace main {
 	    baseDir: string;
 	    dataDir: string;
 	    imagesDir: string;
+	    undoDir: string;
+	    quarantineDir: string;
+	    scansDir: string;
 	    dbPath: string;
 	
 	    static createFrom(source: any = {}) {
@@ -15,9 +18,28 @@ export namespace main {
 	        this.baseDir = source["baseDir"];
 	        this.dataDir = source["dataDir"];
 	        this.imagesDir = source["imagesDir"];
+	        this.undoDir = source["undoDir"];
+	        this.quarantineDir = source["quarantineDir"];
+	        this.scansDir = source["scansDir"];
 	        this.dbPath = source["dbPath"];
 	    }
 	}
+	export class BackupSettings {
+	    interval: string;
+	    folder: string;
+	    keep: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new BackupSettings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.interval = source["interval"];
+	        this.folder = source["folder"];
+	        this.keep = source["keep"];
+	    }
+	}
 	export class BagInfo {
 	    id: number;
 	    serialNo: string;
@@ -32,77 +54,697 @@ export namespace main {
 	    locationNote: string;
 	
 	    static createFrom(source: any = {}) {
-	        return new BagInfo(source);
+	        return new BagInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.serialNo = source["serialNo"];
+	        this.boxId = source["boxId"];
+	        this.boxCode = source["boxCode"];
+	        this.boxName = source["boxName"];
+	        this.locationId = source["locationId"];
+	        this.locationName = source["locationName"];
+	        this.locationRoom = source["locationRoom"];
+	        this.locationShelf = source["locationShelf"];
+	        this.locationCompartment = source["locationCompartment"];
+	        this.locationNote = source["locationNote"];
+	    }
+	}
+	export class BagListing {
+	    bagId: number;
+	    serialNo: string;
+	    setId: number;
+	    setName: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new BagListing(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.bagId = source["bagId"];
+	        this.serialNo = source["serialNo"];
+	        this.setId = source["setId"];
+	        this.setName = source["setName"];
+	    }
+	}
+	export class BarcodeConflict {
+	    setId: number;
+	    setName: string;
+	    productId: number;
+	    productName: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new BarcodeConflict(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.setId = source["setId"];
+	        this.setName = source["setName"];
+	        this.productId = source["productId"];
+	        this.productName = source["productName"];
+	    }
+	}
+	export class Box {
+	    id: number;
+	    locationId: number;
+	    code: string;
+	    name: string;
+	    serialTemplate: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Box(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.locationId = source["locationId"];
+	        this.code = source["code"];
+	        this.name = source["name"];
+	        this.serialTemplate = source["serialTemplate"];
+	    }
+	}
+	export class BoxContents {
+	    box: Box;
+	    locationName: string;
+	    bags: BagListing[];
+	
+	    static createFrom(source: any = {}) {
+	        return new BoxContents(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.box = this.convertValues(source["box"], Box);
+	        this.locationName = source["locationName"];
+	        this.bags = this.convertValues(source["bags"], BagListing);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class BulkResult {
+	    setId: number;
+	    ok: boolean;
+	    error: string;
+	    bagSerial?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new BulkResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.setId = source["setId"];
+	        this.ok = source["ok"];
+	        this.error = source["error"];
+	        this.bagSerial = source["bagSerial"];
+	    }
+	}
+	export class DataLocation {
+	    baseDir: string;
+	    mode: string;
+	    canMove: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new DataLocation(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.baseDir = source["baseDir"];
+	        this.mode = source["mode"];
+	        this.canMove = source["canMove"];
+	    }
+	}
+	export class ImpactSet {
+	    setId: number;
+	    setName: string;
+	    boxCode: string;
+	    bagSerial: string;
+	    trashed: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new ImpactSet(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.setId = source["setId"];
+	        this.setName = source["setName"];
+	        this.boxCode = source["boxCode"];
+	        this.bagSerial = source["bagSerial"];
+	        this.trashed = source["trashed"];
+	    }
+	}
+	export class DeleteImpact {
+	    boxes: Box[];
+	    bagCount: number;
+	    sets: ImpactSet[];
+	    images: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new DeleteImpact(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.boxes = this.convertValues(source["boxes"], Box);
+	        this.bagCount = source["bagCount"];
+	        this.sets = this.convertValues(source["sets"], ImpactSet);
+	        this.images = source["images"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class DownloadSettings {
+	    maxBytes: number;
+	    allowPrivate: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new DownloadSettings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.maxBytes = source["maxBytes"];
+	        this.allowPrivate = source["allowPrivate"];
+	    }
+	}
+	export class DuplicateCandidate {
+	    setId: number;
+	    setName: string;
+	    manufacturerName: string;
+	    boxCode: string;
+	    bagSerial: string;
+	    score: number;
+	    reasons: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new DuplicateCandidate(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.setId = source["setId"];
+	        this.setName = source["setName"];
+	        this.manufacturerName = source["manufacturerName"];
+	        this.boxCode = source["boxCode"];
+	        this.bagSerial = source["bagSerial"];
+	        this.score = source["score"];
+	        this.reasons = source["reasons"];
+	    }
+	}
+	export class DuplicateQuery {
+	    name: string;
+	    manufacturer: string;
+	    products: string[];
+	    barcode: string;
+	    catalogNo: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new DuplicateQuery(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.manufacturer = source["manufacturer"];
+	        this.products = source["products"];
+	        this.barcode = source["barcode"];
+	        this.catalogNo = source["catalogNo"];
+	    }
+	}
+	export class GeneralSettings {
+	    language: string;
+	    defaultSort: string;
+	    thumbnailSize: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new GeneralSettings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.language = source["language"];
+	        this.defaultSort = source["defaultSort"];
+	        this.thumbnailSize = source["thumbnailSize"];
+	    }
+	}
+	export class ImageCleanupResult {
+	    removed: number;
+	    quarantined: number;
+	    freedBytes: number;
+	    cleared: number;
+	    quarantineDir: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ImageCleanupResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.removed = source["removed"];
+	        this.quarantined = source["quarantined"];
+	        this.freedBytes = source["freedBytes"];
+	        this.cleared = source["cleared"];
+	        this.quarantineDir = source["quarantineDir"];
+	    }
+	}
+	export class ImageFile {
+	    path: string;
+	    size: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new ImageFile(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.size = source["size"];
+	    }
+	}
+	export class ImageReference {
+	    setId: number;
+	    setName: string;
+	    photoPath: string;
+	    exists: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new ImageReference(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.setId = source["setId"];
+	        this.setName = source["setName"];
+	        this.photoPath = source["photoPath"];
+	        this.exists = source["exists"];
+	    }
+	}
+	export class ImageReport {
+	    referenced: number;
+	    referencedBytes: number;
+	    orphaned: ImageFile[];
+	    orphanedBytes: number;
+	    missing: ImageReference[];
+	    absolute: ImageReference[];
+	    totalBytes: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new ImageReport(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.referenced = source["referenced"];
+	        this.referencedBytes = source["referencedBytes"];
+	        this.orphaned = this.convertValues(source["orphaned"], ImageFile);
+	        this.orphanedBytes = source["orphanedBytes"];
+	        this.missing = this.convertValues(source["missing"], ImageReference);
+	        this.absolute = this.convertValues(source["absolute"], ImageReference);
+	        this.totalBytes = source["totalBytes"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ImageSettings {
+	    maxDimension: number;
+	    jpegQuality: number;
+	    keepOriginal: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new ImageSettings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.maxDimension = source["maxDimension"];
+	        this.jpegQuality = source["jpegQuality"];
+	        this.keepOriginal = source["keepOriginal"];
+	    }
+	}
+	
+	export class LabelLayout {
+	    id: string;
+	    name: string;
+	    pageWidth: number;
+	    pageHeight: number;
+	    columns: number;
+	    rows: number;
+	    labelWidth: number;
+	    labelHeight: number;
+	    marginTop: number;
+	    marginLeft: number;
+	    pitchX: number;
+	    pitchY: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new LabelLayout(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.pageWidth = source["pageWidth"];
+	        this.pageHeight = source["pageHeight"];
+	        this.columns = source["columns"];
+	        this.rows = source["rows"];
+	        this.labelWidth = source["labelWidth"];
+	        this.labelHeight = source["labelHeight"];
+	        this.marginTop = source["marginTop"];
+	        this.marginLeft = source["marginLeft"];
+	        this.pitchX = source["pitchX"];
+	        this.pitchY = source["pitchY"];
+	    }
+	}
+	export class LabelRequest {
+	    boxIds: number[];
+	    setIds: number[];
+	    layout: string;
+	    format: string;
+	    skipLabels: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new LabelRequest(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.boxIds = source["boxIds"];
+	        this.setIds = source["setIds"];
+	        this.layout = source["layout"];
+	        this.format = source["format"];
+	        this.skipLabels = source["skipLabels"];
+	    }
+	}
+	export class Product {
+	    id: number;
+	    setId: number;
+	    name: string;
+	    kind: string;
+	    barcode: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Product(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.setId = source["setId"];
+	        this.name = source["name"];
+	        this.kind = source["kind"];
+	        this.barcode = source["barcode"];
+	    }
+	}
+	export class SetDetails {
+	    id: number;
+	    name: string;
+	    manufacturerId?: number;
+	    manufacturerName: string;
+	    typeId?: number;
+	    typeName: string;
+	    bag: BagInfo;
+	    photoPath: string;
+	    originalPath: string;
+	    photoSource: string;
+	    barcode: string;
+	    catalogNo: string;
+	    tags: string[];
+	    products: Product[];
+	    deletedAt: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SetDetails(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.manufacturerId = source["manufacturerId"];
+	        this.manufacturerName = source["manufacturerName"];
+	        this.typeId = source["typeId"];
+	        this.typeName = source["typeName"];
+	        this.bag = this.convertValues(source["bag"], BagInfo);
+	        this.photoPath = source["photoPath"];
+	        this.originalPath = source["originalPath"];
+	        this.photoSource = source["photoSource"];
+	        this.barcode = source["barcode"];
+	        this.catalogNo = source["catalogNo"];
+	        this.tags = source["tags"];
+	        this.products = this.convertValues(source["products"], Product);
+	        this.deletedAt = source["deletedAt"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class LookupResult {
+	    code: string;
+	    format: string;
+	    kind: string;
+	    set?: SetDetails;
+	    box?: BoxContents;
+	
+	    static createFrom(source: any = {}) {
+	        return new LookupResult(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.id = source["id"];
-	        this.serialNo = source["serialNo"];
-	        this.boxId = source["boxId"];
-	        this.boxCode = source["boxCode"];
-	        this.boxName = source["boxName"];
-	        this.locationId = source["locationId"];
-	        this.locationName = source["locationName"];
-	        this.locationRoom = source["locationRoom"];
-	        this.locationShelf = source["locationShelf"];
-	        this.locationCompartment = source["locationCompartment"];
-	        this.locationNote = source["locationNote"];
+	        this.code = source["code"];
+	        this.format = source["format"];
+	        this.kind = source["kind"];
+	        this.set = this.convertValues(source["set"], SetDetails);
+	        this.box = this.convertValues(source["box"], BoxContents);
 	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
-	export class Box {
+	export class Manufacturer {
 	    id: number;
-	    locationId: number;
-	    code: string;
 	    name: string;
 	
 	    static createFrom(source: any = {}) {
-	        return new Box(source);
+	        return new Manufacturer(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.id = source["id"];
-	        this.locationId = source["locationId"];
-	        this.code = source["code"];
 	        this.name = source["name"];
 	    }
 	}
-	export class Manufacturer {
-	    id: number;
-	    name: string;
+	export class NormalizeResult {
+	    processed: number;
+	    changed: number;
+	    failed: number;
+	    savedBytes: number;
 	
 	    static createFrom(source: any = {}) {
-	        return new Manufacturer(source);
+	        return new NormalizeResult(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.id = source["id"];
-	        this.name = source["name"];
+	        this.processed = source["processed"];
+	        this.changed = source["changed"];
+	        this.failed = source["failed"];
+	        this.savedBytes = source["savedBytes"];
 	    }
 	}
-	export class Product {
-	    id: number;
-	    setId: number;
+	
+	export class Profile {
+	    id: string;
 	    name: string;
-	    kind: string;
+	    createdAt: string;
+	    baseDir: string;
+	    active: boolean;
 	
 	    static createFrom(source: any = {}) {
-	        return new Product(source);
+	        return new Profile(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.id = source["id"];
-	        this.setId = source["setId"];
 	        this.name = source["name"];
-	        this.kind = source["kind"];
+	        this.createdAt = source["createdAt"];
+	        this.baseDir = source["baseDir"];
+	        this.active = source["active"];
+	    }
+	}
+	export class ScanArea {
+	    x: number;
+	    y: number;
+	    width: number;
+	    height: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new ScanArea(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.x = source["x"];
+	        this.y = source["y"];
+	        this.width = source["width"];
+	        this.height = source["height"];
+	    }
+	}
+	export class ScanCrop {
+	    base64Data: string;
+	    token: string;
+	    x: number;
+	    y: number;
+	    width: number;
+	    height: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new ScanCrop(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.base64Data = source["base64Data"];
+	        this.token = source["token"];
+	        this.x = source["x"];
+	        this.y = source["y"];
+	        this.width = source["width"];
+	        this.height = source["height"];
+	    }
+	}
+	export class ScanOptions {
+	    deviceId: string;
+	    dpi: number;
+	    color: string;
+	    feeder: boolean;
+	    area?: ScanArea;
+	    format: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ScanOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.deviceId = source["deviceId"];
+	        this.dpi = source["dpi"];
+	        this.color = source["color"];
+	        this.feeder = source["feeder"];
+	        this.area = this.convertValues(source["area"], ScanArea);
+	        this.format = source["format"];
 	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
 	export class ScanResult {
 	    base64Data: string;
-	    relPath: string;
+	    token: string;
+	    cropped?: ScanCrop;
+	    items: ScanCrop[];
 	
 	    static createFrom(source: any = {}) {
 	        return new ScanResult(source);
@@ -111,39 +753,49 @@ export namespace main {
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.base64Data = source["base64Data"];
-	        this.relPath = source["relPath"];
+	        this.token = source["token"];
+	        this.cropped = this.convertValues(source["cropped"], ScanCrop);
+	        this.items = this.convertValues(source["items"], ScanCrop);
 	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
-	export class SetDetails {
-	    id: number;
-	    name: string;
-	    manufacturerId?: number;
-	    manufacturerName: string;
-	    typeId?: number;
-	    typeName: string;
-	    bag: BagInfo;
-	    photoPath: string;
-	    photoSource: string;
-	    tags: string[];
-	    products: Product[];
+	export class ScanSettings {
+	    deviceId: string;
+	    dpi: number;
+	    color: string;
+	    format: string;
+	    area?: ScanArea;
+	    autoCrop: boolean;
 	
 	    static createFrom(source: any = {}) {
-	        return new SetDetails(source);
+	        return new ScanSettings(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.id = source["id"];
-	        this.name = source["name"];
-	        this.manufacturerId = source["manufacturerId"];
-	        this.manufacturerName = source["manufacturerName"];
-	        this.typeId = source["typeId"];
-	        this.typeName = source["typeName"];
-	        this.bag = this.convertValues(source["bag"], BagInfo);
-	        this.photoPath = source["photoPath"];
-	        this.photoSource = source["photoSource"];
-	        this.tags = source["tags"];
-	        this.products = this.convertValues(source["products"], Product);
+	        this.deviceId = source["deviceId"];
+	        this.dpi = source["dpi"];
+	        this.color = source["color"];
+	        this.format = source["format"];
+	        this.area = this.convertValues(source["area"], ScanArea);
+	        this.autoCrop = source["autoCrop"];
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -164,6 +816,59 @@ export namespace main {
 		    return a;
 		}
 	}
+	export class ScannerDevice {
+	    id: string;
+	    name: string;
+	    backend: string;
+	    hasFeeder: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new ScannerDevice(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.backend = source["backend"];
+	        this.hasFeeder = source["hasFeeder"];
+	    }
+	}
+	export class SerialChange {
+	    bagId: number;
+	    setId: number;
+	    setName: string;
+	    oldSerial: string;
+	    newSerial: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SerialChange(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.bagId = source["bagId"];
+	        this.setId = source["setId"];
+	        this.setName = source["setName"];
+	        this.oldSerial = source["oldSerial"];
+	        this.newSerial = source["newSerial"];
+	    }
+	}
+	export class SerialSettings {
+	    template: string;
+	    reuseGaps: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new SerialSettings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.template = source["template"];
+	        this.reuseGaps = source["reuseGaps"];
+	    }
+	}
+	
 	export class SetSearchResult {
 	    setId: number;
 	    setName: string;
@@ -192,6 +897,84 @@ export namespace main {
 	        this.thumbnailPath = source["thumbnailPath"];
 	    }
 	}
+	export class TrashSettings {
+	    retentionDays: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new TrashSettings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.retentionDays = source["retentionDays"];
+	    }
+	}
+	export class Settings {
+	    version: number;
+	    general: GeneralSettings;
+	    trash: TrashSettings;
+	    serial: SerialSettings;
+	    image: ImageSettings;
+	    download: DownloadSettings;
+	    scan: ScanSettings;
+	    backup: BackupSettings;
+	
+	    static createFrom(source: any = {}) {
+	        return new Settings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.version = source["version"];
+	        this.general = this.convertValues(source["general"], GeneralSettings);
+	        this.trash = this.convertValues(source["trash"], TrashSettings);
+	        this.serial = this.convertValues(source["serial"], SerialSettings);
+	        this.image = this.convertValues(source["image"], ImageSettings);
+	        this.download = this.convertValues(source["download"], DownloadSettings);
+	        this.scan = this.convertValues(source["scan"], ScanSettings);
+	        this.backup = this.convertValues(source["backup"], BackupSettings);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class SimilarSet {
+	    setId: number;
+	    setName: string;
+	    boxCode: string;
+	    bagSerial: string;
+	    photoPath: string;
+	    distance: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new SimilarSet(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.setId = source["setId"];
+	        this.setName = source["setName"];
+	        this.boxCode = source["boxCode"];
+	        this.bagSerial = source["bagSerial"];
+	        this.photoPath = source["photoPath"];
+	        this.distance = source["distance"];
+	    }
+	}
 	export class StorageLocation {
 	    id: number;
 	    friendlyName: string;
@@ -228,6 +1011,31 @@ export namespace main {
 	        this.name = source["name"];
 	    }
 	}
+	
+	export class TrashedSet {
+	    setId: number;
+	    setName: string;
+	    manufacturerName: string;
+	    boxCode: string;
+	    bagSerial: string;
+	    thumbnailPath: string;
+	    deletedAt: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new TrashedSet(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.setId = source["setId"];
+	        this.setName = source["setName"];
+	        this.manufacturerName = source["manufacturerName"];
+	        this.boxCode = source["boxCode"];
+	        this.bagSerial = source["bagSerial"];
+	        this.thumbnailPath = source["thumbnailPath"];
+	        this.deletedAt = source["deletedAt"];
+	    }
+	}
 	export class Type {
 	    id: number;
 	    name: string;
@@ -242,6 +1050,24 @@ export namespace main {
 	        this.name = source["name"];
 	    }
 	}
+	export class UndoState {
+	    canUndo: boolean;
+	    undoDescription: string;
+	    canRedo: boolean;
+	    redoDescription: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new UndoState(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.canUndo = source["canUndo"];
+	        this.undoDescription = source["undoDescription"];
+	        this.canRedo = source["canRedo"];
+	        this.redoDescription = source["redoDescription"];
+	    }
+	}
 
 }
 
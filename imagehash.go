@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"math"
+	"math/bits"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// SimilarSet is a set whose photo looks like the searched image.
+// Distance is the combined Hamming distance of dHash and pHash (0 = identical, max 128).
+type SimilarSet struct {
+	SetID     int64  `json:"setId"`
+	SetName   string `json:"setName"`
+	BoxCode   string `json:"boxCode"`
+	BagSerial string `json:"bagSerial"`
+	PhotoPath string `json:"photoPath"`
+	Distance  int    `json:"distance"`
+}
+
+const (
+	similarMaxDistance = 30
+	similarMaxResults  = 20
+)
+
+// SearchByImage finds sets whose photos are visually similar to the given image,
+// closest first. The image may be a file path, a data URL or plain base64.
+func (a *App) SearchByImage(input string) ([]SimilarSet, error) {
+	data, err := readImageInput(input)
+	if err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read image: %w", err)
+	}
+	dh, ph := dHash(img), pHash(img)
+
+	rows, err := a.db.Query(`
+		SELECT s.id, s.name, bx.code, b.serial_no, s.photo_path, h.dhash, h.phash
+		FROM sets s
+		JOIN image_hashes h ON h.path = s.photo_path
+		JOIN bags b ON b.id = s.bag_id
+		JOIN boxes bx ON bx.id = b.box_id
+		WHERE s.deleted_at IS NULL`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []SimilarSet
+	for rows.Next() {
+		var r SimilarSet
+		var sd, sp int64
+		if err := rows.Scan(&r.SetID, &r.SetName, &r.BoxCode, &r.BagSerial, &r.PhotoPath, &sd, &sp); err != nil {
+			return nil, err
+		}
+		r.Distance = bits.OnesCount64(dh^uint64(sd)) + bits.OnesCount64(ph^uint64(sp))
+		if r.Distance <= similarMaxDistance {
+			results = append(results, r)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Distance < results[j].Distance })
+	if len(results) > similarMaxResults {
+		results = results[:similarMaxResults]
+	}
+	return results, nil
+}
+
+// BackfillImageHashes hashes set photos that have no hash yet and drops hashes
+// of images no longer used. It returns the number of images hashed.
+func (a *App) BackfillImageHashes() (int, error) {
+	return a.backfillImageHashes(context.Background())
+}
+
+// backfillImageHashes is BackfillImageHashes that stops early once ctx is done.
+func (a *App) backfillImageHashes(ctx context.Context) (int, error) {
+	if _, err := a.db.Exec(`DELETE FROM image_hashes WHERE path NOT IN (SELECT photo_path FROM sets WHERE photo_path IS NOT NULL)`); err != nil {
+		return 0, err
+	}
+
+	rows, err := a.db.Query(`
+		SELECT DISTINCT photo_path FROM sets
+		WHERE photo_path IS NOT NULL AND photo_path != ''
+		  AND photo_path NOT IN (SELECT path FROM image_hashes)`)
+	if err != nil {
+		return 0, err
+	}
+	var paths []string
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		paths = append(paths, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	hashed := 0
+	for _, p := range paths {
+		if ctx.Err() != nil {
+			return hashed, nil
+		}
+		if err := a.storeImageHash(p); err != nil {
+			a.logInfo(fmt.Sprintf("skipping image hash for %s: %v", p, err))
+			continue
+		}
+		hashed++
+	}
+	return hashed, nil
+}
+
+// storeImageHash computes and saves the perceptual hashes of a stored image.
+func (a *App) storeImageHash(relPath string) error {
+	f, err := os.Open(filepath.Join(a.paths().BaseDir, filepath.FromSlash(relPath)))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return err
+	}
+	_, err = a.db.Exec(`INSERT OR REPLACE INTO image_hashes(path, dhash, phash) VALUES (?, ?, ?)`,
+		relPath, int64(dHash(img)), int64(pHash(img)))
+	return err
+}
+
+func (a *App) forgetImageHash(relPath string) {
+	_, _ = a.db.Exec(`DELETE FROM image_hashes WHERE path = ?`, relPath)
+}
+
+// dHash compares neighbouring pixels of a 9x8 grayscale thumbnail.
+func dHash(img image.Image) uint64 {
+	g := grayGrid(img, 9, 8)
+	var h uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			h <<= 1
+			if g[y*9+x] < g[y*9+x+1] {
+				h |= 1
+			}
+		}
+	}
+	return h
+}
+
+// pHash keeps the signs of the low 8x8 DCT frequencies of a 32x32 thumbnail
+// relative to their median.
+func pHash(img image.Image) uint64 {
+	const n = 32
+	g := grayGrid(img, n, n)
+
+	// Separable DCT-II: rows first, then the 8 lowest columns.
+	cos := make([]float64, n*8)
+	for u := 0; u < 8; u++ {
+		for x := 0; x < n; x++ {
+			cos[u*n+x] = math.Cos(float64(2*x+1) * float64(u) * math.Pi / (2 * n))
+		}
+	}
+	rowDCT := make([]float64, n*8)
+	for y := 0; y < n; y++ {
+		for u := 0; u < 8; u++ {
+			sum := 0.0
+			for x := 0; x < n; x++ {
+				sum += g[y*n+x] * cos[u*n+x]
+			}
+			rowDCT[y*8+u] = sum
+		}
+	}
+	coeffs := make([]float64, 64)
+	for v := 0; v < 8; v++ {
+		for u := 0; u < 8; u++ {
+			sum := 0.0
+			for y := 0; y < n; y++ {
+				sum += rowDCT[y*8+u] * cos[v*n+y]
+			}
+			coeffs[v*8+u] = sum
+		}
+	}
+
+	// The DC term only reflects overall brightness, leave it out of the median.
+	sorted := append([]float64(nil), coeffs[1:]...)
+	sort.Float64s(sorted)
+	median := sorted[len(sorted)/2]
+
+	var h uint64
+	for _, c := range coeffs {
+		h <<= 1
+		if c > median {
+			h |= 1
+		}
+	}
+	return h
+}
+
+// grayGrid shrinks img to w x h cells of average luminance. Large images are
+// sampled on a coarse raster, which is plenty for hashing.
+func grayGrid(img image.Image, w, h int) []float64 {
+	b := img.Bounds()
+	bw, bh := b.Dx(), b.Dy()
+	sum := make([]float64, w*h)
+	count := make([]float64, w*h)
+	if bw == 0 || bh == 0 {
+		return sum
+	}
+	step := max(1, max(bw, bh)/256)
+	for y := 0; y < bh; y += step {
+		cy := y * h / bh
+		for x := 0; x < bw; x += step {
+			r, g, bl, _ := img.At(b.Min.X+x, b.Min.Y+y).RGBA()
+			cell := cy*w + x*w/bw
+			sum[cell] += 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(bl)
+			count[cell]++
+		}
+	}
+	for i := range sum {
+		if count[i] > 0 {
+			sum[i] /= count[i]
+		}
+	}
+	return sum
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ImageReport describes the state of the Images folder compared to the database.
+type ImageReport struct {
+	Referenced      int              `json:"referenced"`
+	ReferencedBytes int64            `json:"referencedBytes"`
+	Orphaned        []ImageFile      `json:"orphaned"`
+	OrphanedBytes   int64            `json:"orphanedBytes"`
+	Missing         []ImageReference `json:"missing"`
+	Absolute        []ImageReference `json:"absolute"`
+	TotalBytes      int64            `json:"totalBytes"`
+}
+
+// ImageFile is a file in the Images folder.
+type ImageFile struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+// ImageReference is a set's photo or kept original.
+type ImageReference struct {
+	SetID     int64  `json:"setId"`
+	SetName   string `json:"setName"`
+	PhotoPath string `json:"photoPath"`
+	Exists    bool   `json:"exists"`
+}
+
+// ImageCleanupResult summarises what CleanupImages did.
+type ImageCleanupResult struct {
+	Removed       int    `json:"removed"`
+	Quarantined   int    `json:"quarantined"`
+	FreedBytes    int64  `json:"freedBytes"`
+	Cleared       int    `json:"cleared"`
+	QuarantineDir string `json:"quarantineDir"`
+}
+
+// Uploads are written to a temp file first; leave fresh ones alone.
+const pendingUploadAge = 10 * time.Minute
+
+// CheckImages reports orphaned files, references to missing files and
+// references using absolute paths.
+func (a *App) CheckImages() (ImageReport, error) {
+	var report ImageReport
+
+	rows, err := a.db.Query(`
+		SELECT id, name, photo_path FROM sets WHERE IFNULL(photo_path,'') != ''
+		UNION ALL
+		SELECT id, name, original_path FROM sets WHERE IFNULL(original_path,'') != ''
+		ORDER BY 2`)
+	if err != nil {
+		return report, err
+	}
+	referenced := map[string]bool{}
+	for rows.Next() {
+		var ref ImageReference
+		if err := rows.Scan(&ref.SetID, &ref.SetName, &ref.PhotoPath); err != nil {
+			rows.Close()
+			return report, err
+		}
+		full := ref.PhotoPath
+		if !filepath.IsAbs(full) {
+			full = filepath.Join(a.paths().BaseDir, filepath.FromSlash(full))
+		}
+		info, statErr := os.Stat(full)
+		ref.Exists = statErr == nil
+		if filepath.IsAbs(ref.PhotoPath) {
+			report.Absolute = append(report.Absolute, ref)
+		}
+		if !ref.Exists {
+			report.Missing = append(report.Missing, ref)
+			continue
+		}
+		if !referenced[filepath.Clean(full)] {
+			referenced[filepath.Clean(full)] = true
+			report.Referenced++
+			report.ReferencedBytes += info.Size()
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return report, err
+	}
+
+	orphans, err := a.orphanedImages(referenced)
+	if err != nil {
+		return report, err
+	}
+	report.Orphaned = orphans
+	for _, f := range orphans {
+		report.OrphanedBytes += f.Size
+	}
+	report.TotalBytes = report.ReferencedBytes + report.OrphanedBytes
+	return report, nil
+}
+
+// orphanedImages lists files in the Images folder that no set references.
+func (a *App) orphanedImages(referenced map[string]bool) ([]ImageFile, error) {
+	paths := a.paths()
+	var orphans []ImageFile
+	err := filepath.Walk(paths.ImagesDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return nil
+		}
+		if referenced[filepath.Clean(path)] {
+			return nil
+		}
+		if strings.HasPrefix(info.Name(), ".upload-") && time.Since(info.ModTime()) < pendingUploadAge {
+			return nil
+		}
+		rel, err := filepath.Rel(paths.BaseDir, path)
+		if err != nil {
+			return nil
+		}
+		orphans = append(orphans, ImageFile{Path: filepath.ToSlash(rel), Size: info.Size()})
+		return nil
+	})
+	return orphans, err
+}
+
+// CleanupImages deals with the problems found by CheckImages.
+// orphans: "keep", "delete" or "quarantine" (moved to a dated folder in Data/Quarantine).
+// clearMissing removes photo references whose file no longer exists.
+func (a *App) CleanupImages(orphans string, clearMissing bool) (ImageCleanupResult, error) {
+	paths := a.paths()
+	var result ImageCleanupResult
+	switch orphans {
+	case "", "keep", "delete", "quarantine":
+	default:
+		return result, fmt.Errorf("unknown orphan action %q", orphans)
+	}
+
+	report, err := a.CheckImages()
+	if err != nil {
+		return result, err
+	}
+
+	if orphans == "quarantine" && len(report.Orphaned) > 0 {
+		result.QuarantineDir = filepath.Join(paths.QuarantineDir, time.Now().Format("2006-01-02_150405"))
+		if err := os.MkdirAll(result.QuarantineDir, 0o755); err != nil {
+			return result, err
+		}
+	}
+	for _, f := range report.Orphaned {
+		src := filepath.Join(paths.BaseDir, filepath.FromSlash(f.Path))
+		switch orphans {
+		case "delete":
+			if err := os.Remove(src); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return result, err
+			}
+			result.Removed++
+		case "quarantine":
+			rel, _ := filepath.Rel(paths.ImagesDir, src)
+			dst := filepath.Join(result.QuarantineDir, rel)
+			if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+				return result, err
+			}
+			if err := os.Rename(src, dst); err != nil {
+				return result, err
+			}
+			result.Quarantined++
+		default:
+			continue
+		}
+		result.FreedBytes += f.Size
+		a.forgetImageHash(f.Path)
+	}
+
+	if clearMissing {
+		if err := a.clearMissingImages(report.Missing, &result); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// clearMissingImages removes the references to missing files in one
+// transaction and then reports the sets that lost their photo.
+func (a *App) clearMissingImages(missing []ImageReference, result *ImageCleanupResult) error {
+	tx, err := a.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var removed, updated []int64
+	for _, ref := range missing {
+		var res sql.Result
+		res, err = tx.Exec(`UPDATE sets SET photo_path = NULL, photo_source = NULL WHERE id = ? AND photo_path = ?`, ref.SetID, ref.PhotoPath)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err == nil && n > 0 {
+			result.Cleared++
+			removed = append(removed, ref.SetID)
+		}
+		res, err = tx.Exec(`UPDATE sets SET original_path = NULL WHERE id = ? AND original_path = ?`, ref.SetID, ref.PhotoPath)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err == nil && n > 0 {
+			result.Cleared++
+			updated = append(updated, ref.SetID)
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	for _, ref := range missing {
+		a.forgetImageHash(ref.PhotoPath)
+	}
+	for _, setID := range removed {
+		a.emitEvent(EventImageRemoved, ImageEvent{SetID: setID})
+	}
+	a.emitChange(EventSetUpdated, updated...)
+	return nil
+}
@@ -0,0 +1,386 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"golang.org/x/image/draw"
+)
+
+const (
+	settingImageMaxDimension = "image.max_dimension"
+	settingImageJPEGQuality  = "image.jpeg_quality"
+	settingImageKeepOriginal = "image.keep_original"
+	defaultImageMaxDimension = 2000
+	defaultImageJPEGQuality  = 85
+
+	// maxImagePixels rejects images whose decoded pixels would not fit
+	// comfortably in memory; 100 megapixels take 400 MB.
+	maxImagePixels = 100_000_000
+)
+
+// ImageSettings controls how incoming images are normalised.
+// MaxDimension 0 keeps the original size.
+type ImageSettings struct {
+	MaxDimension int  `json:"maxDimension"`
+	JPEGQuality  int  `json:"jpegQuality"`
+	KeepOriginal bool `json:"keepOriginal"`
+}
+
+// NormalizeResult summarises a NormalizeImages run.
+type NormalizeResult struct {
+	Processed  int   `json:"processed"`
+	Changed    int   `json:"changed"`
+	Failed     int   `json:"failed"`
+	SavedBytes int64 `json:"savedBytes"`
+}
+
+func readImageSettings(q queryer) (ImageSettings, error) {
+	defaults := ImageSettings{MaxDimension: defaultImageMaxDimension, JPEGQuality: defaultImageJPEGQuality}
+	settings := defaults
+	maxDim, err := readSetting(q, settingImageMaxDimension, strconv.Itoa(defaultImageMaxDimension))
+	if err != nil {
+		return settings, err
+	}
+	quality, err := readSetting(q, settingImageJPEGQuality, strconv.Itoa(defaultImageJPEGQuality))
+	if err != nil {
+		return settings, err
+	}
+	keep, err := readSetting(q, settingImageKeepOriginal, "false")
+	if err != nil {
+		return settings, err
+	}
+	if n, err := strconv.Atoi(maxDim); err == nil {
+		settings.MaxDimension = n
+	}
+	if n, err := strconv.Atoi(quality); err == nil {
+		settings.JPEGQuality = n
+	}
+	settings.KeepOriginal, _ = strconv.ParseBool(keep)
+	if err := validateImageSettings(settings); err != nil {
+		return defaults, nil
+	}
+	return settings, nil
+}
+
+func validateImageSettings(settings ImageSettings) error {
+	if settings.MaxDimension != 0 && (settings.MaxDimension < 256 || settings.MaxDimension > 10000) {
+		return errors.New("maximum image size must be between 256 and 10000 pixels, or 0 for no limit")
+	}
+	if settings.JPEGQuality < 1 || settings.JPEGQuality > 100 {
+		return errors.New("JPEG quality must be between 1 and 100")
+	}
+	return nil
+}
+
+func (a *App) GetImageSettings() (ImageSettings, error) {
+	return readImageSettings(a.db)
+}
+
+func writeImageSettings(q queryer, settings ImageSettings) error {
+	if err := writeSetting(q, settingImageMaxDimension, strconv.Itoa(settings.MaxDimension)); err != nil {
+		return err
+	}
+	if err := writeSetting(q, settingImageJPEGQuality, strconv.Itoa(settings.JPEGQuality)); err != nil {
+		return err
+	}
+	return writeSetting(q, settingImageKeepOriginal, strconv.FormatBool(settings.KeepOriginal))
+}
+
+// UpdateImageSettings stores how new images are resized and compressed.
+func (a *App) UpdateImageSettings(settings ImageSettings) error {
+	_, err := a.updateSettings(func(s *Settings) {
+		s.Image = settings
+	})
+	return err
+}
+
+// ingestImage verifies, normalises and stores an incoming image. The format
+// is taken from the content, never from a file name or MIME type. It returns
+// the stored path and, when the image was changed and originals are kept,
+// the path of the untouched original.
+func (a *App) ingestImage(data []byte) (relPath, originalPath string, err error) {
+	ext, err := sniffImage(data)
+	if err != nil {
+		return "", "", err
+	}
+	settings, err := readImageSettings(a.db)
+	if err != nil {
+		return "", "", err
+	}
+	out, outExt, changed, err := normalizeImage(data, settings)
+	if err != nil {
+		return "", "", err
+	}
+	relPath, err = a.storeImage(bytes.NewReader(out), outExt)
+	if err != nil {
+		return "", "", err
+	}
+	if changed && settings.KeepOriginal {
+		if originalPath, err = a.storeImage(bytes.NewReader(data), ext); err != nil {
+			return "", "", err
+		}
+	}
+	return relPath, originalPath, nil
+}
+
+// normalizeImage applies the EXIF orientation, scales the image down to the
+// maximum dimension and re-encodes it as JPEG, or PNG when it has transparency.
+// Images that need none of this are returned unchanged.
+func normalizeImage(data []byte, settings ImageSettings) ([]byte, string, bool, error) {
+	// Check the size from the header before allocating the pixels.
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", false, fmt.Errorf("unable to read image: %w", err)
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > maxImagePixels {
+		return nil, "", false, fmt.Errorf("image is too large (%dx%d pixels)", cfg.Width, cfg.Height)
+	}
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", false, fmt.Errorf("unable to read image: %w", err)
+	}
+	orientation := 1
+	if format == "jpeg" {
+		orientation = jpegOrientation(data)
+	}
+	transparent := false
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		transparent = !o.Opaque()
+	}
+
+	b := img.Bounds()
+	tooLarge := settings.MaxDimension > 0 && max(b.Dx(), b.Dy()) > settings.MaxDimension
+	switch {
+	case tooLarge || orientation > 1:
+	case format == "jpeg":
+		return data, ".jpg", false, nil
+	case format == "png" && transparent:
+		return data, ".png", false, nil
+	}
+
+	if tooLarge {
+		img = scaleDown(img, settings.MaxDimension)
+	}
+	// Orient after scaling; rotating the smaller image is much cheaper.
+	img = applyOrientation(img, orientation)
+
+	var buf bytes.Buffer
+	if transparent {
+		err = png.Encode(&buf, img)
+		return buf.Bytes(), ".png", true, err
+	}
+	err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: settings.JPEGQuality})
+	return buf.Bytes(), ".jpg", true, err
+}
+
+func scaleDown(img image.Image, maxDim int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w >= h {
+		h = max(1, h*maxDim/w)
+		w = maxDim
+	} else {
+		w = max(1, w*maxDim/h)
+		h = maxDim
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+	return dst
+}
+
+// applyOrientation turns an image upright according to its EXIF orientation (1-8).
+func applyOrientation(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+	// Copy pixels directly; At and Set per pixel are slow on large photos.
+	src, ok := img.(*image.NRGBA)
+	if !ok || src.Rect.Min != (image.Point{}) {
+		src = image.NewNRGBA(image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()))
+		draw.Draw(src, src.Rect, img, img.Bounds().Min, draw.Src)
+	}
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2: // mirror horizontally
+				sx, sy = w-1-x, y
+			case 3: // rotate 180°
+				sx, sy = w-1-x, h-1-y
+			case 4: // mirror vertically
+				sx, sy = x, h-1-y
+			case 5: // transpose
+				sx, sy = y, x
+			case 6: // rotate 90° clockwise
+				sx, sy = y, h-1-x
+			case 7: // transverse
+				sx, sy = w-1-y, h-1-x
+			case 8: // rotate 90° counter-clockwise
+				sx, sy = w-1-y, x
+			}
+			si, di := src.PixOffset(sx, sy), dst.PixOffset(x, y)
+			copy(dst.Pix[di:di+4], src.Pix[si:si+4])
+		}
+	}
+	return dst
+}
+
+// jpegOrientation reads the EXIF orientation tag of a JPEG, returning 1 when absent.
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+	pos := 2
+	for pos+4 <= len(data) {
+		if data[pos] != 0xFF {
+			return 1
+		}
+		marker := data[pos+1]
+		size := int(binary.BigEndian.Uint16(data[pos+2:]))
+		if marker == 0xDA || size < 2 || pos+2+size > len(data) {
+			// Start of scan: no metadata follows.
+			return 1
+		}
+		segment := data[pos+4 : pos+2+size]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return exifOrientation(segment[6:])
+		}
+		pos += 2 + size
+	}
+	return 1
+}
+
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < entries; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			if o := int(order.Uint16(tiff[entry+8:])); o >= 1 && o <= 8 {
+				return o
+			}
+			return 1
+		}
+	}
+	return 1
+}
+
+// NormalizeImages applies the current image settings to every stored set photo.
+// It is meant to be run once after enabling normalisation or changing its settings.
+func (a *App) NormalizeImages() (NormalizeResult, error) {
+	var result NormalizeResult
+	rows, err := a.db.Query(`SELECT DISTINCT photo_path FROM sets WHERE photo_path IS NOT NULL AND photo_path != ''`)
+	if err != nil {
+		return result, err
+	}
+	var paths []string
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			rows.Close()
+			return result, err
+		}
+		paths = append(paths, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return result, err
+	}
+
+	for _, oldPath := range paths {
+		if !a.isStoredImage(oldPath) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(a.paths().BaseDir, filepath.FromSlash(oldPath)))
+		if err != nil {
+			result.Failed++
+			continue
+		}
+		result.Processed++
+		newPath, originalPath, err := a.ingestImage(data)
+		if err != nil {
+			a.logInfo(fmt.Sprintf("failed to normalise %s: %v", oldPath, err))
+			result.Failed++
+			continue
+		}
+		if newPath == oldPath {
+			continue
+		}
+		setIDs, err := a.replacePhotoPath(oldPath, newPath, originalPath)
+		if err != nil {
+			return result, err
+		}
+		if info, err := os.Stat(filepath.Join(a.paths().BaseDir, filepath.FromSlash(newPath))); err == nil {
+			result.SavedBytes += int64(len(data)) - info.Size()
+		}
+		result.Changed++
+		if hashErr := a.storeImageHash(newPath); hashErr != nil {
+			a.logInfo(fmt.Sprintf("failed to hash image %s: %v", newPath, hashErr))
+		}
+		_ = a.deleteLocalImage(oldPath)
+		for _, setID := range setIDs {
+			a.emitEvent(EventImageAttached, ImageEvent{SetID: setID, Path: newPath})
+		}
+	}
+	return result, nil
+}
+
+// replacePhotoPath points every set showing oldPath to newPath and returns
+// the sets it changed.
+func (a *App) replacePhotoPath(oldPath, newPath, originalPath string) ([]int64, error) {
+	tx, err := a.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	setIDs, err := listIDsTx(tx, `SELECT id FROM sets WHERE photo_path = ?`, oldPath)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = tx.Exec(`UPDATE sets SET photo_path = ?, original_path = IFNULL(original_path, NULLIF(?, '')) WHERE photo_path = ?`,
+		newPath, originalPath, oldPath); err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return setIDs, nil
+}
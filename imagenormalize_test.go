@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"strings"
+	"testing"
+)
+
+// gridImage returns a 3x2 image whose pixels are told apart by their red value:
+//
+//	a b c
+//	d e f
+func gridImage() *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+	for i, r := range "abcdef" {
+		img.SetNRGBA(i%3, i/3, color.NRGBA{R: uint8(r), A: 255})
+	}
+	return img
+}
+
+// gridRows reads an image produced from gridImage back as letters.
+func gridRows(img image.Image) []string {
+	b := img.Bounds()
+	var rows []string
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		var row []byte
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, _, _, _ := img.At(x, y).RGBA()
+			row = append(row, byte(r>>8))
+		}
+		rows = append(rows, string(row))
+	}
+	return rows
+}
+
+func TestApplyOrientation(t *testing.T) {
+	tests := []struct {
+		orientation int
+		want        string
+	}{
+		{0, "abc def"},
+		{1, "abc def"},
+		{2, "cba fed"},
+		{3, "fed cba"},
+		{4, "def abc"},
+		{5, "ad be cf"},
+		{6, "da eb fc"},
+		{7, "fc eb da"},
+		{8, "cf be ad"},
+		{9, "abc def"},
+	}
+	for _, tt := range tests {
+		got := strings.Join(gridRows(applyOrientation(gridImage(), tt.orientation)), " ")
+		if got != tt.want {
+			t.Errorf("orientation %d: got %q, want %q", tt.orientation, got, tt.want)
+		}
+	}
+}
+
+func TestApplyOrientationOtherImageTypes(t *testing.T) {
+	// An RGBA image placed away from the origin, as SubImage returns.
+	src := image.NewRGBA(image.Rect(10, 20, 13, 22))
+	for i, r := range "abcdef" {
+		src.Set(10+i%3, 20+i/3, color.RGBA{R: uint8(r), A: 255})
+	}
+	if got := strings.Join(gridRows(applyOrientation(src, 6)), " "); got != "da eb fc" {
+		t.Errorf("RGBA: got %q", got)
+	}
+	sub := gridImage().SubImage(image.Rect(1, 0, 3, 2))
+	if got := strings.Join(gridRows(applyOrientation(sub, 3)), " "); got != "fe cb" {
+		t.Errorf("sub-image: got %q", got)
+	}
+}
+
+// exifSegment builds an APP1 segment holding only an orientation tag.
+func exifSegment(order binary.ByteOrder, orientation uint16) []byte {
+	tiff := make([]byte, 8+2+12)
+	if order == binary.LittleEndian {
+		copy(tiff, "II")
+	} else {
+		copy(tiff, "MM")
+	}
+	order.PutUint16(tiff[2:], 42)
+	order.PutUint32(tiff[4:], 8)
+	order.PutUint16(tiff[8:], 1)
+	order.PutUint16(tiff[10:], 0x0112)
+	order.PutUint16(tiff[12:], 3)
+	order.PutUint32(tiff[14:], 1)
+	order.PutUint16(tiff[18:], orientation)
+	return appSegment(0xE1, append([]byte("Exif\x00\x00"), tiff...))
+}
+
+func appSegment(marker byte, payload []byte) []byte {
+	seg := []byte{0xFF, marker, 0, 0}
+	binary.BigEndian.PutUint16(seg[2:], uint16(len(payload)+2))
+	return append(seg, payload...)
+}
+
+// withSegments inserts segments right after the start-of-image marker.
+func withSegments(jpg []byte, segments ...[]byte) []byte {
+	out := append([]byte{}, jpg[:2]...)
+	for _, s := range segments {
+		out = append(out, s...)
+	}
+	return append(out, jpg[2:]...)
+}
+
+// testJPEG encodes a w x h image, red on the left half and blue on the right.
+func testJPEG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.RGBA{R: 255, A: 255}
+			if x >= w/2 {
+				c = color.RGBA{B: 255, A: 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95}); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestJPEGOrientation(t *testing.T) {
+	base := testJPEG(t, 4, 2)
+	for o := uint16(1); o <= 8; o++ {
+		for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+			if got := jpegOrientation(withSegments(base, exifSegment(order, o))); got != int(o) {
+				t.Errorf("%v orientation %d: got %d", order, o, got)
+			}
+		}
+	}
+
+	// Other segments before the EXIF data are skipped.
+	jfif := appSegment(0xE0, []byte("JFIF\x00\x01\x02\x00\x00\x01\x00\x01\x00\x00"))
+	xmp := appSegment(0xE1, []byte("http://ns.adobe.com/xap/1.0/\x00<x/>"))
+	if got := jpegOrientation(withSegments(base, jfif, xmp, exifSegment(binary.BigEndian, 6))); got != 6 {
+		t.Errorf("after JFIF and XMP: got %d", got)
+	}
+}
+
+func TestJPEGOrientationMalformed(t *testing.T) {
+	base := testJPEG(t, 4, 2)
+	exif := exifSegment(binary.LittleEndian, 6)
+	tiffAt := 4 + 6 // marker, length, "Exif\0\0"
+
+	patch := func(seg []byte, at int, b ...byte) []byte {
+		seg = append([]byte{}, seg...)
+		copy(seg[at:], b)
+		return seg
+	}
+	tests := map[string][]byte{
+		"empty":                nil,
+		"no start of image":    append([]byte{0x00, 0x00}, exif...),
+		"only start of image":  base[:2],
+		"truncated segment":    withSegments(base, exif)[:2+len(exif)-3],
+		"length past the end":  append(base[:2:2], patch(exif, 2, 0xFF, 0xFF)...),
+		"length below two":     withSegments(base, patch(exif, 2, 0x00, 0x01)),
+		"not a marker":         withSegments(base, []byte{0x00, 0xE1, 0x00, 0x02}),
+		"short TIFF header":    withSegments(base, appSegment(0xE1, []byte("Exif\x00\x00II*\x00"))),
+		"unknown byte order":   withSegments(base, patch(exif, tiffAt, 'X', 'X')),
+		"IFD past the end":     withSegments(base, patch(exif, tiffAt+4, 0xF0, 0xFF, 0xFF, 0x7F)),
+		"entries past the end": withSegments(base, patch(exif, tiffAt+8, 0xFF, 0x00, 0x10, 0x01)),
+		"orientation zero":     withSegments(base, exifSegment(binary.LittleEndian, 0)),
+		"orientation nine":     withSegments(base, exifSegment(binary.LittleEndian, 9)),
+		"orientation too big":  withSegments(base, exifSegment(binary.BigEndian, 0xFFFF)),
+	}
+	for name, data := range tests {
+		if got := jpegOrientation(data); got != 1 {
+			t.Errorf("%s: got %d, want 1", name, got)
+		}
+	}
+}
+
+func TestNormalizeImageOrientsJPEG(t *testing.T) {
+	data := withSegments(testJPEG(t, 40, 20), exifSegment(binary.BigEndian, 6))
+	out, ext, changed, err := normalizeImage(data, ImageSettings{JPEGQuality: 90})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ext != ".jpg" || !changed {
+		t.Fatalf("got %s, changed %v", ext, changed)
+	}
+	img, err := jpeg.Decode(bytes.NewReader(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != 20 || b.Dy() != 40 {
+		t.Fatalf("size %dx%d, want 20x40", b.Dx(), b.Dy())
+	}
+	// Turned clockwise, the red left half ends up on top.
+	top, _, _, _ := img.At(10, 5).RGBA()
+	_, _, bottom, _ := img.At(10, 35).RGBA()
+	if top>>8 < 200 || bottom>>8 < 200 {
+		t.Errorf("colours not where expected: top red %d, bottom blue %d", top>>8, bottom>>8)
+	}
+}
+
+func TestNormalizeImageRejectsHugeImages(t *testing.T) {
+	var buf bytes.Buffer
+	if err := gif.Encode(&buf, image.NewPaletted(image.Rect(0, 0, 2, 2), color.Palette{color.Black, color.White}), nil); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	// Claim a 65535x65535 logical screen in the header.
+	binary.LittleEndian.PutUint16(data[6:], 0xFFFF)
+	binary.LittleEndian.PutUint16(data[8:], 0xFFFF)
+
+	_, _, _, err := normalizeImage(data, ImageSettings{JPEGQuality: 90})
+	if err == nil || !strings.Contains(err.Error(), "too large") {
+		t.Fatalf("got %v", err)
+	}
+}
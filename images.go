@@ -1,21 +1,15 @@
 package main
 
 import (
-	"bytes"
 	"database/sql"
 	"encoding/base64"
 	"errors"
 	"fmt"
-	"io"
 	"net/http"
-	"net/url"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
-	"time"
 
-	"github.com/google/uuid"
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
@@ -42,31 +36,17 @@ func (a *App) AttachImageFromFile(setID int64, filePath string) (string, error)
 		return "", errors.New("file path is empty")
 	}
 
-	src, err := os.Open(filePath)
+	data, err := os.ReadFile(filePath)
 	if err != nil {
 		return "", err
 	}
-	defer src.Close()
-
-	ext := strings.ToLower(filepath.Ext(filePath))
-	if ext == "" {
-		ext = ".png"
-	}
-	fileName := fmt.Sprintf("%s%s", uuid.NewString(), ext)
-	relPath := filepath.ToSlash(filepath.Join("Images", fileName))
-	destPath := filepath.Join(a.paths.BaseDir, relPath)
 
-	dst, err := os.Create(destPath)
+	relPath, originalPath, err := a.ingestImage(data)
 	if err != nil {
 		return "", err
 	}
-	if _, err := io.Copy(dst, src); err != nil {
-		dst.Close()
-		return "", err
-	}
-	_ = dst.Close()
 
-	if err := a.setImagePath(setID, relPath, "file"); err != nil {
+	if err := a.setImagePath(setID, relPath, originalPath, "file"); err != nil {
 		return "", err
 	}
 	return relPath, nil
@@ -81,68 +61,45 @@ func (a *App) AttachImageFromURL(setID int64, rawURL string) (string, error) {
 		return "", errors.New("url is required")
 	}
 
-	parsed, err := url.Parse(rawURL)
+	data, err := a.downloadImage(rawURL)
 	if err != nil {
 		return "", err
 	}
-
-	client := &http.Client{Timeout: 15 * time.Second}
-	resp, err := client.Get(parsed.String())
+	relPath, originalPath, err := a.ingestImage(data)
 	if err != nil {
 		return "", err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return "", fmt.Errorf("failed to download image (status %d)", resp.StatusCode)
+	if err := a.setImagePath(setID, relPath, originalPath, "url"); err != nil {
+		return "", err
 	}
+	return relPath, nil
+}
 
-	ext := strings.ToLower(filepath.Ext(parsed.Path))
-	if ext == "" {
-		contentType := resp.Header.Get("Content-Type")
-		switch {
-		case strings.Contains(contentType, "png"):
-			ext = ".png"
-		case strings.Contains(contentType, "jpeg"), strings.Contains(contentType, "jpg"):
-			ext = ".jpg"
-		case strings.Contains(contentType, "gif"):
-			ext = ".gif"
-		default:
-			ext = ".png"
-		}
+// SaveCroppedImage stores an edited image sent by the UI. ext is only kept for
+// compatibility; the format is detected from the data.
+func (a *App) SaveCroppedImage(setID int64, base64Data string, ext string) (string, error) {
+	if setID <= 0 {
+		return "", errors.New("set is required")
 	}
-
-	fileName := fmt.Sprintf("%s%s", uuid.NewString(), ext)
-	relPath := filepath.ToSlash(filepath.Join("Images", fileName))
-	destPath := filepath.Join(a.paths.BaseDir, relPath)
-
-	out, err := os.Create(destPath)
+	buf, err := decodeBase64Image(base64Data)
 	if err != nil {
 		return "", err
 	}
-	if _, err := io.Copy(out, resp.Body); err != nil {
-		out.Close()
+
+	relPath, originalPath, err := a.ingestImage(buf)
+	if err != nil {
 		return "", err
 	}
-	_ = out.Close()
 
-	if err := a.setImagePath(setID, relPath, "url"); err != nil {
+	if err := a.setImagePath(setID, relPath, originalPath, "cropped"); err != nil {
 		return "", err
 	}
 	return relPath, nil
 }
 
-func (a *App) SaveCroppedImage(setID int64, base64Data string, ext string) (string, error) {
-	if setID <= 0 {
-		return "", errors.New("set is required")
-	}
-	if ext == "" {
-		ext = ".png"
-	}
-	if !strings.HasPrefix(ext, ".") {
-		ext = "." + ext
-	}
-
+// decodeBase64Image accepts plain base64 or a data URL.
+func decodeBase64Image(base64Data string) ([]byte, error) {
 	data := strings.TrimSpace(base64Data)
 	if strings.Contains(data, ",") {
 		parts := strings.SplitN(data, ",", 2)
@@ -151,24 +108,13 @@ func (a *App) SaveCroppedImage(setID int64, base64Data string, ext string) (stri
 
 	buf, err := base64.StdEncoding.DecodeString(data)
 	if err != nil {
-		return "", fmt.Errorf("unable to decode image: %w", err)
-	}
-
-	fileName := fmt.Sprintf("%s%s", uuid.NewString(), ext)
-	relPath := filepath.ToSlash(filepath.Join("Images", fileName))
-	destPath := filepath.Join(a.paths.BaseDir, relPath)
-
-	if err := os.WriteFile(destPath, buf, 0o644); err != nil {
-		return "", err
-	}
-
-	if err := a.setImagePath(setID, relPath, "cropped"); err != nil {
-		return "", err
+		return nil, fmt.Errorf("unable to decode image: %w", err)
 	}
-	return relPath, nil
+	return buf, nil
 }
 
-func (a *App) setImagePath(setID int64, relPath, source string) error {
+// setImagePath points a set at a stored image and releases the files it used before.
+func (a *App) setImagePath(setID int64, relPath, originalPath, source string) error {
 	tx, err := a.db.Begin()
 	if err != nil {
 		return err
@@ -179,12 +125,12 @@ func (a *App) setImagePath(setID int64, relPath, source string) error {
 		}
 	}()
 
-	var oldPath sql.NullString
-	if err = tx.QueryRow(`SELECT photo_path FROM sets WHERE id = ?`, setID).Scan(&oldPath); err != nil {
+	var oldPath, oldOriginal sql.NullString
+	if err = tx.QueryRow(`SELECT photo_path, original_path FROM sets WHERE id = ?`, setID).Scan(&oldPath, &oldOriginal); err != nil {
 		return err
 	}
 
-	if _, err = tx.Exec(`UPDATE sets SET photo_path = ?, photo_source = ? WHERE id = ?`, relPath, source, setID); err != nil {
+	if _, err = tx.Exec(`UPDATE sets SET photo_path = ?, original_path = NULLIF(?, ''), photo_source = ? WHERE id = ?`, relPath, originalPath, source, setID); err != nil {
 		return err
 	}
 
@@ -192,28 +138,40 @@ func (a *App) setImagePath(setID int64, relPath, source string) error {
 		return err
 	}
 
-	if oldPath.Valid && oldPath.String != "" && oldPath.String != relPath {
-		_ = deleteLocalImage(a.paths.ImagesDir, oldPath.String)
+	if hashErr := a.storeImageHash(relPath); hashErr != nil {
+		a.logInfo(fmt.Sprintf("failed to hash image %s: %v", relPath, hashErr))
 	}
+	for _, old := range []sql.NullString{oldPath, oldOriginal} {
+		if old.Valid && old.String != "" {
+			_ = a.deleteLocalImage(old.String)
+		}
+	}
+	a.emitEvent(EventImageAttached, ImageEvent{SetID: setID, Path: relPath})
 	return nil
 }
 
-func deleteLocalImage(imagesDir, relPath string) error {
+// deleteLocalImage removes a stored image once no set references it any more.
+func (a *App) deleteLocalImage(relPath string) error {
+	paths := a.paths()
 	if relPath == "" {
 		return nil
 	}
+	if refs, err := imageRefCount(a.db, relPath); err != nil || refs > 0 {
+		return err
+	}
 	target := relPath
 	if !filepath.IsAbs(target) {
-		target = filepath.Join(filepath.Dir(imagesDir), relPath)
+		target = filepath.Join(paths.BaseDir, relPath)
 	}
 	// Basic safety: only delete inside Images directory.
-	if !strings.HasPrefix(filepath.Clean(target), filepath.Clean(imagesDir)) {
+	if !strings.HasPrefix(filepath.Clean(target), filepath.Clean(paths.ImagesDir)) {
 		return fmt.Errorf("refusing to delete outside images directory")
 	}
 	err := os.Remove(target)
 	if err != nil && !errors.Is(err, os.ErrNotExist) {
 		return err
 	}
+	a.forgetImageHash(relPath)
 	return nil
 }
 
@@ -224,6 +182,12 @@ func (a *App) logInfo(msg string) {
 	}
 }
 
+func (a *App) logWarning(msg string) {
+	if a.ctx != nil {
+		runtime.LogWarning(a.ctx, msg)
+	}
+}
+
 // ReadFileAsBase64 reads a file and returns it as a base64 data URL
 func (a *App) ReadFileAsBase64(filePath string) (string, error) {
 	data, err := os.ReadFile(filePath)
@@ -258,7 +222,7 @@ func (a *App) GetImageAsBase64(relPath string) (string, error) {
 	if filepath.IsAbs(relPath) {
 		fullPath = relPath
 	} else {
-		fullPath = filepath.Join(a.paths.BaseDir, relPath)
+		fullPath = filepath.Join(a.paths().BaseDir, relPath)
 	}
 	
 	return a.ReadFileAsBase64(fullPath)
@@ -272,121 +236,97 @@ func (a *App) ResolveImagePath(relPath string) string {
 	if filepath.IsAbs(relPath) {
 		return "file:///" + filepath.ToSlash(relPath)
 	}
-	full := filepath.Join(a.paths.BaseDir, relPath)
+	full := filepath.Join(a.paths().BaseDir, relPath)
 	return "file:///" + filepath.ToSlash(full)
 }
 
-// ScanImage uses Windows WIA (Windows Image Acquisition) to scan from a connected scanner
-// Returns the path to the scanned image file
+// ScanImage scans a single page using the saved scan settings.
+// Returns the token of the staged scan
 func (a *App) ScanImage() (string, error) {
-	// Create temp file path for scanned image
-	fileName := fmt.Sprintf("scan_%s.png", uuid.NewString())
-	tempPath := filepath.Join(a.paths.ImagesDir, fileName)
-
-	// PowerShell script to use WIA for scanning
-	psScript := fmt.Sprintf(`
-Add-Type -AssemblyName System.Drawing
-
-# Create WIA DeviceManager
-$deviceManager = New-Object -ComObject WIA.DeviceManager
-
-# Get first scanner device
-$device = $null
-foreach ($d in $deviceManager.DeviceInfos) {
-    if ($d.Type -eq 1) {  # Scanner type
-        $device = $d.Connect()
-        break
-    }
-}
-
-if ($device -eq $null) {
-    Write-Error "No scanner found"
-    exit 1
-}
-
-# Get first item (scanner bed)
-$item = $device.Items.Item(1)
-
-# Configure for color scan at 200 DPI
-$item.Properties.Item("6146").Value = 1  # Color intent
-$item.Properties.Item("6147").Value = 200  # Horizontal DPI
-$item.Properties.Item("6148").Value = 200  # Vertical DPI
-
-# Scan
-$imageFile = $item.Transfer("{B96B3CAE-0728-11D3-9D7B-0000F81EF32E}")  # PNG format
-
-# Save to file
-$imageFile.SaveFile("%s")
-
-Write-Output "OK"
-`, strings.ReplaceAll(tempPath, "\\", "\\\\"))
-
-	// Execute PowerShell
-	cmd := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", psScript)
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	err := cmd.Run()
+	settings, err := readScanSettings(a.db)
 	if err != nil {
-		errMsg := stderr.String()
-		if strings.Contains(errMsg, "No scanner found") {
-			return "", errors.New("no scanner found - please connect a scanner via USB")
-		}
-		return "", fmt.Errorf("scan failed: %s", errMsg)
+		return "", err
 	}
-
-	// Check if file was created
-	if _, err := os.Stat(tempPath); os.IsNotExist(err) {
-		return "", errors.New("scan failed - no image created")
+	pages, err := a.ScanPages(settings.options())
+	if err != nil {
+		return "", err
 	}
-
-	// Return relative path
-	relPath := filepath.ToSlash(filepath.Join("Images", fileName))
-	return relPath, nil
+	return pages[0], nil
 }
 
-// ScanResult contains both the base64 data for preview and the relative path for storage
+// ScanResult contains both the base64 data for preview and the token for AttachScannedImage.
+// With auto-crop enabled, Cropped is the scan trimmed to its content and Items
+// holds one image per item when several were placed on the glass.
 type ScanResult struct {
-	Base64Data string `json:"base64Data"`
-	RelPath    string `json:"relPath"`
+	Base64Data string     `json:"base64Data"`
+	Token      string     `json:"token"`
+	Cropped    *ScanCrop  `json:"cropped"`
+	Items      []ScanCrop `json:"items"`
 }
 
 // ScanImageToBase64 scans and returns the image as base64 for preview/cropping plus the path
 func (a *App) ScanImageToBase64() (*ScanResult, error) {
-	relPath, err := a.ScanImage()
+	settings, err := readScanSettings(a.db)
 	if err != nil {
 		return nil, err
 	}
+	pages, err := a.ScanPages(settings.options())
+	if err != nil {
+		return nil, err
+	}
+	token := pages[0]
 
-	fullPath := filepath.Join(a.paths.BaseDir, relPath)
+	fullPath, err := a.stagedScanPath(token)
+	if err != nil {
+		return nil, err
+	}
 	data, err := os.ReadFile(fullPath)
 	if err != nil {
 		return nil, err
 	}
 
-	// Return as data URL
-	base64Data := base64.StdEncoding.EncodeToString(data)
-	return &ScanResult{
-		Base64Data: fmt.Sprintf("data:image/png;base64,%s", base64Data),
-		RelPath:    relPath,
-	}, nil
+	result := &ScanResult{Base64Data: dataURL(data), Token: token}
+	if settings.AutoCrop {
+		result.Cropped, result.Items, err = a.autoCropScan(token, settings.Format)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
 }
 
-// AttachScannedImage attaches a previously scanned image to a set
-func (a *App) AttachScannedImage(setID int64, scannedPath string) (string, error) {
+// dataURL encodes an image as a data URL for the frontend.
+func dataURL(data []byte) string {
+	return fmt.Sprintf("data:%s;base64,%s", http.DetectContentType(data), base64.StdEncoding.EncodeToString(data))
+}
+
+// AttachScannedImage moves a staged scan into the image store and attaches it to a set.
+// Returns the stored image path.
+func (a *App) AttachScannedImage(setID int64, token string) (string, error) {
 	if setID <= 0 {
 		return "", errors.New("set is required")
 	}
-	if scannedPath == "" {
-		return "", errors.New("scanned path is required")
+	scanFile, err := a.stagedScanPath(token)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(scanFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", errors.New("scan has expired - please scan again")
+	}
+	if err != nil {
+		return "", err
+	}
+	relPath, originalPath, err := a.ingestImage(data)
+	if err != nil {
+		return "", err
 	}
 
-	// The scannedPath should already be a relative path like "Images/scan_xxx.png"
-	if err := a.setImagePath(setID, scannedPath, "scan"); err != nil {
+	if err := a.setImagePath(setID, relPath, originalPath, "scan"); err != nil {
 		return "", err
 	}
-	return scannedPath, nil
+	_ = os.Remove(scanFile)
+	return relPath, nil
 }
 
 // RemoveImage removes the image from a set and deletes the file
@@ -395,23 +335,26 @@ func (a *App) RemoveImage(setID int64) error {
 		return errors.New("set is required")
 	}
 
-	// Get current image path
-	var oldPath sql.NullString
-	err := a.db.QueryRow(`SELECT photo_path FROM sets WHERE id = ?`, setID).Scan(&oldPath)
+	// Get current image paths
+	var oldPath, oldOriginal sql.NullString
+	err := a.db.QueryRow(`SELECT photo_path, original_path FROM sets WHERE id = ?`, setID).Scan(&oldPath, &oldOriginal)
 	if err != nil {
 		return err
 	}
 
 	// Clear the image path in DB
-	_, err = a.db.Exec(`UPDATE sets SET photo_path = NULL, photo_source = NULL WHERE id = ?`, setID)
+	_, err = a.db.Exec(`UPDATE sets SET photo_path = NULL, original_path = NULL, photo_source = NULL WHERE id = ?`, setID)
 	if err != nil {
 		return err
 	}
 
-	// Delete the file if it exists
-	if oldPath.Valid && oldPath.String != "" {
-		_ = deleteLocalImage(a.paths.ImagesDir, oldPath.String)
+	// Delete the files unless another set still uses them
+	for _, old := range []sql.NullString{oldPath, oldOriginal} {
+		if old.Valid && old.String != "" {
+			_ = a.deleteLocalImage(old.String)
+		}
 	}
 
+	a.emitEvent(EventImageRemoved, ImageEvent{SetID: setID})
 	return nil
 }
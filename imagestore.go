@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// storeImage copies r into Images/ named after the SHA-256 of its content, so
+// attaching the same picture twice stores it once. It returns the path
+// relative to the base folder as saved in sets.photo_path.
+func (a *App) storeImage(r io.Reader, ext string) (string, error) {
+	paths := a.paths()
+	ext = strings.ToLower(ext)
+	if ext != "" && !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+
+	tmp, err := os.CreateTemp(paths.ImagesDir, ".upload-*")
+	if err != nil {
+		return "", err
+	}
+	tmpPath := tmp.Name()
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, hash), r); err != nil {
+		tmp.Close()
+		_ = os.Remove(tmpPath)
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmpPath)
+		return "", err
+	}
+
+	relPath := filepath.ToSlash(filepath.Join("Images", hex.EncodeToString(hash.Sum(nil))+ext))
+	destPath := filepath.Join(paths.BaseDir, relPath)
+	if _, err := os.Stat(destPath); err == nil {
+		_ = os.Remove(tmpPath)
+		return relPath, nil
+	}
+	if err := os.Rename(tmpPath, destPath); err != nil {
+		_ = os.Remove(tmpPath)
+		return "", err
+	}
+	return relPath, nil
+}
+
+// sniffImage identifies a supported image format from its magic bytes and
+// returns the file extension to store it under.
+func sniffImage(data []byte) (string, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, 0xD8, 0xFF}):
+		return ".jpg", nil
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		return ".png", nil
+	case bytes.HasPrefix(data, []byte("GIF87a")), bytes.HasPrefix(data, []byte("GIF89a")):
+		return ".gif", nil
+	case bytes.HasPrefix(data, []byte("BM")) && len(data) > 14:
+		return ".bmp", nil
+	case len(data) > 12 && string(data[:4]) == "RIFF" && string(data[8:12]) == "WEBP":
+		return ".webp", nil
+	}
+	if len(data) == 0 {
+		return "", errors.New("image is empty")
+	}
+	return "", fmt.Errorf("unsupported image format (%s); use JPEG, PNG, GIF, BMP or WebP", http.DetectContentType(data))
+}
+
+// imageRefCount returns how many sets, including those in the trash, use an
+// image as photo or kept original.
+func imageRefCount(q queryer, relPath string) (int, error) {
+	var n int
+	err := q.QueryRow(`SELECT COUNT(*) FROM sets WHERE photo_path = ? OR original_path = ?`, relPath, relPath).Scan(&n)
+	return n, err
+}
+
+// isStoredImage reports whether relPath points into the Images folder.
+func (a *App) isStoredImage(relPath string) bool {
+	paths := a.paths()
+	if relPath == "" || filepath.IsAbs(relPath) {
+		return false
+	}
+	full := filepath.Clean(filepath.Join(paths.BaseDir, relPath))
+	return strings.HasPrefix(full, filepath.Clean(paths.ImagesDir)+string(filepath.Separator))
+}
+
+// dedupeImages moves images saved under random names to content-addressed
+// names and points every set at the shared copy. It is safe to run again and
+// returns the number of files that became redundant and were removed.
+func (a *App) dedupeImages() (int, error) {
+	rows, err := a.db.Query(`SELECT DISTINCT photo_path FROM sets WHERE photo_path IS NOT NULL AND photo_path != ''`)
+	if err != nil {
+		return 0, err
+	}
+	var paths []string
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		paths = append(paths, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	renamed := map[string]string{}
+	for _, p := range paths {
+		if !a.isStoredImage(p) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(a.paths().BaseDir, filepath.FromSlash(p)))
+		if err != nil {
+			// Missing files are left for the image maintenance tool.
+			continue
+		}
+		ext, err := sniffImage(data)
+		if err != nil {
+			ext = filepath.Ext(p)
+		}
+		stored, err := a.storeImage(bytes.NewReader(data), ext)
+		if err != nil {
+			return 0, fmt.Errorf("store %s: %w", p, err)
+		}
+		if stored != p {
+			renamed[p] = stored
+		}
+	}
+	if len(renamed) == 0 {
+		return 0, nil
+	}
+
+	tx, err := a.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	for oldPath, newPath := range renamed {
+		if _, err = tx.Exec(`UPDATE sets SET photo_path = ? WHERE photo_path = ?`, newPath, oldPath); err != nil {
+			return 0, err
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for oldPath := range renamed {
+		if a.deleteLocalImage(oldPath) == nil {
+			removed++
+		}
+	}
+	return removed, nil
+}
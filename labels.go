@@ -0,0 +1,352 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/skip2/go-qrcode"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// Label QR codes encode one of these prefixes followed by the database ID.
+const (
+	labelCodeBoxPrefix = "samla:box:"
+	labelCodeSetPrefix = "samla:set:"
+)
+
+const mmPerPt = 25.4 / 72
+
+// LabelLayout describes a sticker sheet. All lengths are in millimetres.
+type LabelLayout struct {
+	ID          string  `json:"id"`
+	Name        string  `json:"name"`
+	PageWidth   float64 `json:"pageWidth"`
+	PageHeight  float64 `json:"pageHeight"`
+	Columns     int     `json:"columns"`
+	Rows        int     `json:"rows"`
+	LabelWidth  float64 `json:"labelWidth"`
+	LabelHeight float64 `json:"labelHeight"`
+	MarginTop   float64 `json:"marginTop"`
+	MarginLeft  float64 `json:"marginLeft"`
+	PitchX      float64 `json:"pitchX"`
+	PitchY      float64 `json:"pitchY"`
+}
+
+var labelLayouts = []LabelLayout{
+	{ID: "avery-l7159", Name: "Avery L7159 (3 × 8, 63.5 × 33.9 mm)", PageWidth: 210, PageHeight: 297, Columns: 3, Rows: 8, LabelWidth: 63.5, LabelHeight: 33.9, MarginTop: 12.9, MarginLeft: 7.2, PitchX: 66, PitchY: 33.9},
+	{ID: "avery-l7160", Name: "Avery L7160 (3 × 7, 63.5 × 38.1 mm)", PageWidth: 210, PageHeight: 297, Columns: 3, Rows: 7, LabelWidth: 63.5, LabelHeight: 38.1, MarginTop: 15.15, MarginLeft: 7.2, PitchX: 66, PitchY: 38.1},
+	{ID: "avery-l7163", Name: "Avery L7163 (2 × 7, 99.1 × 38.1 mm)", PageWidth: 210, PageHeight: 297, Columns: 2, Rows: 7, LabelWidth: 99.1, LabelHeight: 38.1, MarginTop: 15.15, MarginLeft: 4.65, PitchX: 101.6, PitchY: 38.1},
+	{ID: "avery-l7651", Name: "Avery L7651 (5 × 13, 38.1 × 21.2 mm)", PageWidth: 210, PageHeight: 297, Columns: 5, Rows: 13, LabelWidth: 38.1, LabelHeight: 21.2, MarginTop: 10.7, MarginLeft: 4.75, PitchX: 40.6, PitchY: 21.2},
+	{ID: "avery-5160", Name: "Avery 5160 (Letter, 3 × 10, 2.625 × 1 in)", PageWidth: 215.9, PageHeight: 279.4, Columns: 3, Rows: 10, LabelWidth: 66.675, LabelHeight: 25.4, MarginTop: 12.7, MarginLeft: 4.7625, PitchX: 69.85, PitchY: 25.4},
+}
+
+// LabelRequest selects what to print. SkipLabels leaves the first positions of
+// a partly used sheet empty.
+type LabelRequest struct {
+	BoxIDs     []int64 `json:"boxIds"`
+	SetIDs     []int64 `json:"setIds"`
+	Layout     string  `json:"layout"`
+	Format     string  `json:"format"`
+	SkipLabels int     `json:"skipLabels"`
+}
+
+// label is the printable content of one sticker.
+type label struct {
+	title    string
+	subtitle string
+	detail   string
+	code     string
+}
+
+// ListLabelLayouts returns the supported sticker sheets.
+func (a *App) ListLabelLayouts() []LabelLayout {
+	return labelLayouts
+}
+
+func findLabelLayout(id string) (LabelLayout, error) {
+	if id == "" {
+		return labelLayouts[0], nil
+	}
+	for _, l := range labelLayouts {
+		if l.ID == id {
+			return l, nil
+		}
+	}
+	return LabelLayout{}, fmt.Errorf("unknown label layout %q", id)
+}
+
+func (a *App) collectLabels(req LabelRequest) ([]label, error) {
+	var labels []label
+	for _, id := range req.BoxIDs {
+		var l label
+		if err := a.db.QueryRow(`
+			SELECT bx.code, IFNULL(bx.name,''), IFNULL(loc.friendly_name,'')
+			FROM boxes bx
+			LEFT JOIN storage_locations loc ON loc.id = bx.location_id
+			WHERE bx.id = ?`, id).Scan(&l.title, &l.subtitle, &l.detail); err != nil {
+			return nil, fmt.Errorf("box %d: %w", id, err)
+		}
+		l.code = fmt.Sprintf("%s%d", labelCodeBoxPrefix, id)
+		labels = append(labels, l)
+	}
+	for _, id := range req.SetIDs {
+		set, err := a.GetSet(id)
+		if err != nil {
+			return nil, fmt.Errorf("set %d: %w", id, err)
+		}
+		labels = append(labels, label{
+			title:    set.Bag.BoxCode + " / " + set.Bag.SerialNo,
+			subtitle: set.Name,
+			detail:   set.ManufacturerName,
+			code:     fmt.Sprintf("%s%d", labelCodeSetPrefix, id),
+		})
+	}
+	if len(labels) == 0 {
+		return nil, errors.New("nothing selected to print")
+	}
+	return labels, nil
+}
+
+// labelSlots places labels on pages, honouring the skipped positions on the first sheet.
+func labelSlots(layout LabelLayout, skip, count int) (pages int, pos func(i int) (page int, x, y float64)) {
+	perPage := layout.Columns * layout.Rows
+	if skip < 0 || skip >= perPage {
+		skip = 0
+	}
+	pages = (skip + count + perPage - 1) / perPage
+	return pages, func(i int) (int, float64, float64) {
+		slot := skip + i
+		page := slot / perPage
+		cell := slot % perPage
+		col, row := cell%layout.Columns, cell/layout.Columns
+		return page, layout.MarginLeft + float64(col)*layout.PitchX, layout.MarginTop + float64(row)*layout.PitchY
+	}
+}
+
+// labelMetrics derives font sizes (pt) and the QR square (mm) from the label height.
+func labelMetrics(layout LabelLayout) (pad, qr, titlePt, textPt float64) {
+	pad = 2
+	qr = layout.LabelHeight - 2*pad
+	if limit := layout.LabelWidth * 0.45; qr > limit {
+		qr = limit
+	}
+	titlePt = min(max(layout.LabelHeight*0.4, 7), 16)
+	textPt = titlePt * 0.65
+	return pad, qr, titlePt, textPt
+}
+
+func qrBitmap(code string) ([][]bool, error) {
+	q, err := qrcode.New(code, qrcode.Medium)
+	if err != nil {
+		return nil, err
+	}
+	return q.Bitmap(), nil
+}
+
+// RenderLabelsSVG returns one SVG document per sheet for previewing or printing.
+func (a *App) RenderLabelsSVG(req LabelRequest) ([]string, error) {
+	layout, err := findLabelLayout(req.Layout)
+	if err != nil {
+		return nil, err
+	}
+	labels, err := a.collectLabels(req)
+	if err != nil {
+		return nil, err
+	}
+
+	pageCount, pos := labelSlots(layout, req.SkipLabels, len(labels))
+	pages := make([]strings.Builder, pageCount)
+	pad, qrSize, titlePt, textPt := labelMetrics(layout)
+	for i, l := range labels {
+		page, x, y := pos(i)
+		b := &pages[page]
+		bits, err := qrBitmap(l.code)
+		if err != nil {
+			return nil, err
+		}
+
+		fmt.Fprintf(b, `<g transform="translate(%.2f %.2f)">`, x, y)
+		fmt.Fprintf(b, `<clipPath id="c%d"><rect width="%.2f" height="%.2f"/></clipPath><g clip-path="url(#c%d)">`, i, layout.LabelWidth, layout.LabelHeight, i)
+
+		module := qrSize / float64(len(bits))
+		fmt.Fprintf(b, `<path transform="translate(%.2f %.2f) scale(%.4f)" fill="#000" d="`, pad, (layout.LabelHeight-qrSize)/2, module)
+		for row, line := range bits {
+			for col := 0; col < len(line); {
+				if !line[col] {
+					col++
+					continue
+				}
+				start := col
+				for col < len(line) && line[col] {
+					col++
+				}
+				fmt.Fprintf(b, "M%d %dh%dv1h-%dz", start, row, col-start, col-start)
+			}
+		}
+		b.WriteString(`"/>`)
+
+		textX := pad + qrSize + 2
+		lineY := pad + titlePt*mmPerPt
+		fmt.Fprintf(b, `<text x="%.2f" y="%.2f" font-family="Helvetica, Arial, sans-serif" font-weight="bold" font-size="%.2f">%s</text>`,
+			textX, lineY, titlePt*mmPerPt, html.EscapeString(l.title))
+		for _, line := range []string{l.subtitle, l.detail} {
+			if line == "" {
+				continue
+			}
+			lineY += textPt * mmPerPt * 1.3
+			fmt.Fprintf(b, `<text x="%.2f" y="%.2f" font-family="Helvetica, Arial, sans-serif" font-size="%.2f">%s</text>`,
+				textX, lineY, textPt*mmPerPt, html.EscapeString(line))
+		}
+		b.WriteString(`</g></g>`)
+	}
+
+	docs := make([]string, pageCount)
+	for i := range pages {
+		docs[i] = fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%gmm" height="%gmm" viewBox="0 0 %g %g">%s</svg>`,
+			layout.PageWidth, layout.PageHeight, layout.PageWidth, layout.PageHeight, pages[i].String())
+	}
+	return docs, nil
+}
+
+// renderLabelsPDF builds a printable PDF of the requested labels.
+func (a *App) renderLabelsPDF(req LabelRequest) ([]byte, error) {
+	layout, err := findLabelLayout(req.Layout)
+	if err != nil {
+		return nil, err
+	}
+	labels, err := a.collectLabels(req)
+	if err != nil {
+		return nil, err
+	}
+
+	pdf := fpdf.NewCustom(&fpdf.InitType{
+		UnitStr: "mm",
+		Size:    fpdf.SizeType{Wd: layout.PageWidth, Ht: layout.PageHeight},
+	})
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.SetFillColor(0, 0, 0)
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+
+	_, pos := labelSlots(layout, req.SkipLabels, len(labels))
+	pad, qrSize, titlePt, textPt := labelMetrics(layout)
+	textWidth := layout.LabelWidth - qrSize - 2*pad - 2
+	currentPage := -1
+	for i, l := range labels {
+		page, x, y := pos(i)
+		for currentPage < page {
+			pdf.AddPage()
+			currentPage++
+		}
+
+		bits, err := qrBitmap(l.code)
+		if err != nil {
+			return nil, err
+		}
+		module := qrSize / float64(len(bits))
+		qx, qy := x+pad, y+(layout.LabelHeight-qrSize)/2
+		for row, line := range bits {
+			for col := 0; col < len(line); {
+				if !line[col] {
+					col++
+					continue
+				}
+				start := col
+				for col < len(line) && line[col] {
+					col++
+				}
+				pdf.Rect(qx+float64(start)*module, qy+float64(row)*module, float64(col-start)*module, module, "F")
+			}
+		}
+
+		textX := x + pad + qrSize + 2
+		lineY := y + pad + titlePt*mmPerPt
+		pdf.SetFont("Helvetica", "B", titlePt)
+		pdf.Text(textX, lineY, fitText(pdf, tr(l.title), textWidth))
+		pdf.SetFont("Helvetica", "", textPt)
+		for _, line := range []string{l.subtitle, l.detail} {
+			if line == "" {
+				continue
+			}
+			lineY += textPt * mmPerPt * 1.3
+			pdf.Text(textX, lineY, fitText(pdf, tr(line), textWidth))
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// fitText shortens s with an ellipsis until it fits into width at the current font.
+func fitText(pdf *fpdf.Fpdf, s string, width float64) string {
+	if pdf.GetStringWidth(s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 && pdf.GetStringWidth(string(runes)+"...") > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "..."
+}
+
+// ExportLabels asks for a destination and writes the label sheets as PDF or SVG.
+// SVG output with several sheets is written as one numbered file per sheet.
+func (a *App) ExportLabels(req LabelRequest) (string, error) {
+	format := strings.ToLower(strings.TrimSpace(req.Format))
+	if format == "" {
+		format = "pdf"
+	}
+	if format != "pdf" && format != "svg" {
+		return "", fmt.Errorf("unsupported label format %q", req.Format)
+	}
+
+	defaultName := fmt.Sprintf("samla-labels-%s.%s", time.Now().Format("2006-01-02"), format)
+	savePath, err := runtime.SaveFileDialog(a.ctx, runtime.SaveDialogOptions{
+		Title:           "Export Labels",
+		DefaultFilename: defaultName,
+		Filters: []runtime.FileFilter{
+			{DisplayName: strings.ToUpper(format) + " Files (*." + format + ")", Pattern: "*." + format},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	if savePath == "" {
+		return "", nil // User cancelled
+	}
+	if !strings.HasSuffix(strings.ToLower(savePath), "."+format) {
+		savePath += "." + format
+	}
+
+	if format == "pdf" {
+		data, err := a.renderLabelsPDF(req)
+		if err != nil {
+			return "", err
+		}
+		return savePath, os.WriteFile(savePath, data, 0o644)
+	}
+
+	docs, err := a.RenderLabelsSVG(req)
+	if err != nil {
+		return "", err
+	}
+	if len(docs) == 1 {
+		return savePath, os.WriteFile(savePath, []byte(docs[0]), 0o644)
+	}
+	base := strings.TrimSuffix(savePath, filepath.Ext(savePath))
+	for i, doc := range docs {
+		if err := os.WriteFile(fmt.Sprintf("%s-%d.svg", base, i+1), []byte(doc), 0o644); err != nil {
+			return "", err
+		}
+	}
+	return base + "-1.svg", nil
+}
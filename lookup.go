@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/oned"
+	"github.com/makiuchi-d/gozxing/qrcode"
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/webp"
+)
+
+// LookupResult is what a scanned label or barcode resolved to.
+// Kind is "set", "box" or empty when the code is not known.
+type LookupResult struct {
+	Code   string       `json:"code"`
+	Format string       `json:"format"`
+	Kind   string       `json:"kind"`
+	Set    *SetDetails  `json:"set"`
+	Box    *BoxContents `json:"box"`
+}
+
+// BoxContents is a box with the bags stored in it.
+type BoxContents struct {
+	Box          Box          `json:"box"`
+	LocationName string       `json:"locationName"`
+	Bags         []BagListing `json:"bags"`
+}
+
+type BagListing struct {
+	BagID    int64  `json:"bagId"`
+	SerialNo string `json:"serialNo"`
+	SetID    int64  `json:"setId"`
+	SetName  string `json:"setName"`
+}
+
+// LookupByImage decodes a QR code or barcode from a photo and resolves it to a
+// set or box. The image may be a file path or base64 data as accepted by SaveCroppedImage.
+func (a *App) LookupByImage(input string) (LookupResult, error) {
+	data, err := readImageInput(input)
+	if err != nil {
+		return LookupResult{}, err
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return LookupResult{}, fmt.Errorf("unable to read image: %w", err)
+	}
+
+	code, format, err := decodeBarcode(img)
+	if err != nil {
+		return LookupResult{}, err
+	}
+	return a.resolveCode(code, format)
+}
+
+// readImageInput loads image bytes from a file path, a data URL or plain base64.
+func readImageInput(input string) ([]byte, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return nil, errors.New("image is required")
+	}
+	if strings.HasPrefix(input, "data:") {
+		return decodeBase64Image(input)
+	}
+	if _, err := os.Stat(input); err == nil {
+		return os.ReadFile(input)
+	}
+	return decodeBase64Image(input)
+}
+
+// decodeBarcode tries QR first, then the EAN/UPC family and Code 128.
+func decodeBarcode(img image.Image) (string, string, error) {
+	bmp, err := gozxing.NewBinaryBitmapFromImage(img)
+	if err != nil {
+		return "", "", err
+	}
+	hints := map[gozxing.DecodeHintType]interface{}{
+		gozxing.DecodeHintType_TRY_HARDER: true,
+	}
+	readers := []gozxing.Reader{
+		qrcode.NewQRCodeReader(),
+		oned.NewMultiFormatUPCEANReader(hints),
+		oned.NewCode128Reader(),
+	}
+	for _, r := range readers {
+		res, err := r.Decode(bmp, hints)
+		if err == nil {
+			return res.GetText(), res.GetBarcodeFormat().String(), nil
+		}
+	}
+	return "", "", errors.New("no QR code or barcode found in image")
+}
+
+// resolveCode maps decoded label text to a set or box. Besides the samla:set:
+// and samla:box: codes printed on labels it understands EAN/UPC barcodes of
+// sets and products, "BOX / SERIAL" and bare box codes.
+func (a *App) resolveCode(code, format string) (LookupResult, error) {
+	result := LookupResult{Code: code, Format: format}
+	code = strings.TrimSpace(code)
+
+	switch {
+	case strings.HasPrefix(code, labelCodeSetPrefix):
+		id, err := strconv.ParseInt(strings.TrimPrefix(code, labelCodeSetPrefix), 10, 64)
+		if err != nil {
+			return result, nil
+		}
+		return a.withSet(result, id)
+	case strings.HasPrefix(code, labelCodeBoxPrefix):
+		id, err := strconv.ParseInt(strings.TrimPrefix(code, labelCodeBoxPrefix), 10, 64)
+		if err != nil {
+			return result, nil
+		}
+		return a.withBox(result, id)
+	}
+
+	if digits := normalizeBarcode(code); isDigits(digits) && validateBarcode(digits) == nil {
+		setID, err := a.setIDByBarcode(digits)
+		if err == nil {
+			return a.withSet(result, setID)
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return result, err
+		}
+	}
+
+	if boxCode, serial, ok := strings.Cut(code, "/"); ok {
+		var setID int64
+		err := a.db.QueryRow(`
+			SELECT s.id FROM sets s
+			JOIN bags b ON b.id = s.bag_id
+			JOIN boxes bx ON bx.id = b.box_id
+			WHERE s.deleted_at IS NULL AND LOWER(bx.code) = ? AND b.serial_no = ?`, normalizeLower(boxCode), strings.TrimSpace(serial)).Scan(&setID)
+		if err == nil {
+			return a.withSet(result, setID)
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return result, err
+		}
+	}
+
+	var boxID int64
+	err := a.db.QueryRow(`SELECT id FROM boxes WHERE LOWER(code) = ?`, normalizeLower(code)).Scan(&boxID)
+	if err == nil {
+		return a.withBox(result, boxID)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return result, err
+	}
+	return result, nil
+}
+
+// withSet fills in the set. A set in the trash is no match, like one that was purged.
+func (a *App) withSet(result LookupResult, setID int64) (LookupResult, error) {
+	set, err := a.GetSet(setID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return result, nil
+	}
+	if err != nil {
+		return result, err
+	}
+	if set.DeletedAt != "" {
+		return result, nil
+	}
+	result.Kind = "set"
+	result.Set = &set
+	return result, nil
+}
+
+func (a *App) withBox(result LookupResult, boxID int64) (LookupResult, error) {
+	box, err := a.GetBoxContents(boxID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return result, nil
+	}
+	if err != nil {
+		return result, err
+	}
+	result.Kind = "box"
+	result.Box = &box
+	return result, nil
+}
+
+// GetBoxContents returns a box with its location and the bags inside, ordered by serial.
+// Bags whose set is in the trash are left out.
+func (a *App) GetBoxContents(boxID int64) (BoxContents, error) {
+	var c BoxContents
+	if err := a.db.QueryRow(`
+		SELECT bx.id, bx.location_id, bx.code, IFNULL(bx.name,''), IFNULL(bx.serial_template,''), IFNULL(loc.friendly_name,'')
+		FROM boxes bx
+		LEFT JOIN storage_locations loc ON loc.id = bx.location_id
+		WHERE bx.id = ?`, boxID).Scan(&c.Box.ID, &c.Box.LocationID, &c.Box.Code, &c.Box.Name, &c.Box.SerialTemplate, &c.LocationName); err != nil {
+		return c, err
+	}
+
+	rows, err := a.db.Query(`
+		SELECT b.id, b.serial_no, IFNULL(s.id, 0), IFNULL(s.name,'')
+		FROM bags b
+		LEFT JOIN sets s ON s.bag_id = b.id
+		WHERE b.box_id = ? AND (s.id IS NULL OR s.deleted_at IS NULL)
+		ORDER BY b.serial_no`, boxID)
+	if err != nil {
+		return c, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var bag BagListing
+		if err := rows.Scan(&bag.BagID, &bag.SerialNo, &bag.SetID, &bag.SetName); err != nil {
+			return c, err
+		}
+		c.Bags = append(c.Bags, bag)
+	}
+	return c, rows.Err()
+}
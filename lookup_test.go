@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestLookupSkipsTrashedSets(t *testing.T) {
+	a := newTestApp(t)
+	setID := addTestSet(t, a, "trashed")
+	if _, err := a.UpdateSetBarcode(setID, "4006381333931"); err != nil {
+		t.Fatal(err)
+	}
+	set, err := a.GetSet(setID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	codes := []string{
+		fmt.Sprintf("%s%d", labelCodeSetPrefix, setID),
+		"4006381333931",
+		set.Bag.BoxCode + " / " + set.Bag.SerialNo,
+	}
+
+	lookup := func(code string) LookupResult {
+		t.Helper()
+		result, err := a.resolveCode(code, "QR_CODE")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+	for _, code := range codes {
+		if result := lookup(code); result.Kind != "set" || result.Set.ID != setID {
+			t.Fatalf("%q before trashing: %+v", code, result)
+		}
+	}
+
+	if err := a.DeleteSet(setID); err != nil {
+		t.Fatal(err)
+	}
+	for _, code := range codes {
+		if result := lookup(code); result.Kind != "" || result.Set != nil {
+			t.Errorf("%q found a trashed set: %+v", code, result)
+		}
+	}
+
+	if err := a.RestoreSet(setID); err != nil {
+		t.Fatal(err)
+	}
+	for _, code := range codes {
+		if result := lookup(code); result.Kind != "set" {
+			t.Errorf("%q after restoring: %+v", code, result)
+		}
+	}
+}
@@ -66,11 +66,12 @@ func (h *FileHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Build full path
-	fullPath := filepath.Join(h.app.paths.BaseDir, relPath)
+	baseDir := h.app.paths().BaseDir
+	fullPath := filepath.Join(baseDir, relPath)
 
 	// Security check: ensure path is within BaseDir
 	cleanPath := filepath.Clean(fullPath)
-	if !strings.HasPrefix(cleanPath, filepath.Clean(h.app.paths.BaseDir)) {
+	if !strings.HasPrefix(cleanPath, filepath.Clean(baseDir)) {
 		http.NotFound(w, r)
 		return
 	}
@@ -10,10 +10,11 @@ type StorageLocation struct {
 }
 
 type Box struct {
-	ID         int64  `json:"id"`
-	LocationID int64  `json:"locationId"`
-	Code       string `json:"code"`
-	Name       string `json:"name"`
+	ID             int64  `json:"id"`
+	LocationID     int64  `json:"locationId"`
+	Code           string `json:"code"`
+	Name           string `json:"name"`
+	SerialTemplate string `json:"serialTemplate"`
 }
 
 type Bag struct {
@@ -33,10 +34,11 @@ type Type struct {
 }
 
 type Product struct {
-	ID    int64  `json:"id"`
-	SetID int64  `json:"setId"`
-	Name  string `json:"name"`
-	Kind  string `json:"kind"`
+	ID      int64  `json:"id"`
+	SetID   int64  `json:"setId"`
+	Name    string `json:"name"`
+	Kind    string `json:"kind"`
+	Barcode string `json:"barcode"`
 }
 
 type BagInfo struct {
@@ -62,9 +64,13 @@ type SetDetails struct {
 	TypeName         string    `json:"typeName"`
 	Bag              BagInfo   `json:"bag"`
 	PhotoPath        string    `json:"photoPath"`
+	OriginalPath     string    `json:"originalPath"`
 	PhotoSource      string    `json:"photoSource"`
+	Barcode          string    `json:"barcode"`
+	CatalogNo        string    `json:"catalogNo"`
 	Tags             []string  `json:"tags"`
 	Products         []Product `json:"products"`
+	DeletedAt        string    `json:"deletedAt"`
 }
 
 type SetSearchResult struct {
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+const (
+	// defaultProfileID is the collection stored directly in the base folder,
+	// where all data lived before profiles existed.
+	defaultProfileID = "default"
+	profilesFileName = "profiles.json"
+	profilesDirName  = "Profiles"
+
+	// EventProfileSwitched is emitted after another profile was opened.
+	EventProfileSwitched = "profile:switched"
+)
+
+// Profile is an independent collection with its own database and images.
+type Profile struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	CreatedAt string `json:"createdAt"`
+	BaseDir   string `json:"baseDir"`
+	Active    bool   `json:"active"`
+}
+
+// storedProfile is a profile as written to profiles.json.
+type storedProfile struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	CreatedAt string `json:"createdAt"`
+}
+
+type profilesFile struct {
+	Active   string          `json:"active"`
+	Profiles []storedProfile `json:"profiles"`
+}
+
+// profileRegistry guards profiles.json in the root folder, which lists the
+// profiles and remembers the active one across restarts. mode tells how the
+// root folder was chosen, see DataLocation.
+type profileRegistry struct {
+	mu   sync.Mutex
+	root string
+	mode string
+}
+
+func (r *profileRegistry) load() (profilesFile, error) {
+	var f profilesFile
+	data, err := os.ReadFile(filepath.Join(r.root, profilesFileName))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return f, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &f); err != nil {
+			return f, fmt.Errorf("failed to read profiles: %w", err)
+		}
+	}
+	if f.findIndex(defaultProfileID) < 0 {
+		f.Profiles = append([]storedProfile{{ID: defaultProfileID, Name: "Default"}}, f.Profiles...)
+	}
+	if f.findIndex(f.Active) < 0 {
+		f.Active = defaultProfileID
+	}
+	return f, nil
+}
+
+func (r *profileRegistry) save(f profilesFile) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(r.root, profilesFileName)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// baseDir is the folder holding the database and images of a profile.
+func (r *profileRegistry) baseDir(id string) string {
+	return filepath.Join(r.root, profileSubdir(id))
+}
+
+// profileSubdir is the folder of a profile relative to the root folder.
+func profileSubdir(id string) string {
+	if id == defaultProfileID {
+		return ""
+	}
+	return filepath.Join(profilesDirName, id)
+}
+
+func (f profilesFile) findIndex(id string) int {
+	for i, p := range f.Profiles {
+		if p.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (r *profileRegistry) profile(f profilesFile, p storedProfile) Profile {
+	return Profile{ID: p.ID, Name: p.Name, CreatedAt: p.CreatedAt, BaseDir: r.baseDir(p.ID), Active: p.ID == f.Active}
+}
+
+// ListProfiles returns all profiles, the default one first.
+func (a *App) ListProfiles() ([]Profile, error) {
+	r := &a.profiles
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	f, err := r.load()
+	if err != nil {
+		return nil, err
+	}
+	profiles := make([]Profile, 0, len(f.Profiles))
+	for _, p := range f.Profiles {
+		profiles = append(profiles, r.profile(f, p))
+	}
+	return profiles, nil
+}
+
+// CreateProfile adds an empty collection. It is not opened until SwitchProfile is called.
+func (a *App) CreateProfile(name string) (Profile, error) {
+	name, err := cleanProfileName(name)
+	if err != nil {
+		return Profile{}, err
+	}
+	r := &a.profiles
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	f, err := r.load()
+	if err != nil {
+		return Profile{}, err
+	}
+	for _, p := range f.Profiles {
+		if strings.EqualFold(p.Name, name) {
+			return Profile{}, fmt.Errorf("a profile named %q already exists", name)
+		}
+	}
+
+	base := profileSlug(name)
+	id := base
+	for n := 2; f.findIndex(id) >= 0 || dirExists(r.baseDir(id)); n++ {
+		id = base + "-" + strconv.Itoa(n)
+	}
+	if err := ensureDirs(appPathsFor(r.baseDir(id))); err != nil {
+		return Profile{}, err
+	}
+
+	p := storedProfile{ID: id, Name: name, CreatedAt: time.Now().UTC().Format(time.RFC3339)}
+	f.Profiles = append(f.Profiles, p)
+	if err := r.save(f); err != nil {
+		return Profile{}, err
+	}
+	return r.profile(f, p), nil
+}
+
+// RenameProfile changes the display name of a profile; its folder keeps the original ID.
+func (a *App) RenameProfile(id, name string) error {
+	name, err := cleanProfileName(name)
+	if err != nil {
+		return err
+	}
+	r := &a.profiles
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	f, err := r.load()
+	if err != nil {
+		return err
+	}
+	i := f.findIndex(id)
+	if i < 0 {
+		return errors.New("profile not found")
+	}
+	for _, p := range f.Profiles {
+		if p.ID != id && strings.EqualFold(p.Name, name) {
+			return fmt.Errorf("a profile named %q already exists", name)
+		}
+	}
+	f.Profiles[i].Name = name
+	return r.save(f)
+}
+
+// DeleteProfile removes a profile together with its database and images.
+// The default profile and the profile in use cannot be deleted.
+func (a *App) DeleteProfile(id string) error {
+	r := &a.profiles
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	f, err := r.load()
+	if err != nil {
+		return err
+	}
+	i := f.findIndex(id)
+	if i < 0 {
+		return errors.New("profile not found")
+	}
+	if id == defaultProfileID {
+		return errors.New("the default profile cannot be deleted")
+	}
+	if id == f.Active {
+		return errors.New("switch to another profile before deleting this one")
+	}
+	f.Profiles = append(f.Profiles[:i], f.Profiles[i+1:]...)
+	if err := r.save(f); err != nil {
+		return err
+	}
+	return os.RemoveAll(r.baseDir(id))
+}
+
+// SwitchProfile closes the current collection and opens another one. If the
+// other collection cannot be opened the current one stays in use.
+func (a *App) SwitchProfile(id string) error {
+	r := &a.profiles
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	f, err := r.load()
+	if err != nil {
+		return err
+	}
+	i := f.findIndex(id)
+	if i < 0 {
+		return errors.New("profile not found")
+	}
+	if id == f.Active && a.db.isOpen() {
+		return nil
+	}
+	if err := a.openCollection(appPathsFor(r.baseDir(id))); err != nil {
+		return err
+	}
+	f.Active = id
+	if err := r.save(f); err != nil {
+		return err
+	}
+	a.emitEvent(EventProfileSwitched, r.profile(f, f.Profiles[i]))
+	return nil
+}
+
+func cleanProfileName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", errors.New("profile name is required")
+	}
+	if len([]rune(name)) > 60 {
+		return "", errors.New("profile name must be at most 60 characters")
+	}
+	return name, nil
+}
+
+// profileSlug turns a name into a folder-safe ID.
+func profileSlug(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			b.WriteRune(r)
+			dash = false
+		} else if b.Len() > 0 && !dash {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	slug := strings.TrimSuffix(b.String(), "-")
+	if slug == "" || slug == defaultProfileID {
+		slug = "profile"
+	}
+	return slug
+}
+
+func dirExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
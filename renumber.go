@@ -0,0 +1,132 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// SerialChange maps a bag's serial before and after renumbering.
+type SerialChange struct {
+	BagID     int64  `json:"bagId"`
+	SetID     int64  `json:"setId"`
+	SetName   string `json:"setName"`
+	OldSerial string `json:"oldSerial"`
+	NewSerial string `json:"newSerial"`
+}
+
+// MoveBox moves a box with all its bags to another location.
+func (a *App) MoveBox(boxID, locationID int64) error {
+	if locationID <= 0 {
+		return errors.New("location is required")
+	}
+	var exists bool
+	if err := a.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM storage_locations WHERE id = ?)`, locationID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return errors.New("location not found")
+	}
+	res, err := a.db.Exec(`UPDATE boxes SET location_id = ? WHERE id = ?`, locationID, boxID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return errors.New("box not found")
+	}
+	a.emitChange(EventBoxUpdated, boxID)
+	return nil
+}
+
+// RenumberBags assigns dense serials 1, 2, ... to every bag in a box using the box's serial template.
+// sortBy: "serial" (default), "name", "added"
+func (a *App) RenumberBags(boxID int64, sortBy string) ([]SerialChange, error) {
+	orderClause := "b.serial_no"
+	switch sortBy {
+	case "name":
+		orderClause = "LOWER(IFNULL(s.name,'')), b.serial_no"
+	case "added":
+		orderClause = "IFNULL(s.id, 0), b.id"
+	}
+
+	tx, err := a.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var exists bool
+	if err = tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM boxes WHERE id = ?)`, boxID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		err = errors.New("box not found")
+		return nil, err
+	}
+	scheme, _, err := boxSerialScheme(tx, boxID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(fmt.Sprintf(`
+		SELECT b.id, IFNULL(s.id, 0), IFNULL(s.name,''), b.serial_no
+		FROM bags b
+		LEFT JOIN sets s ON s.bag_id = b.id
+		WHERE b.box_id = ?
+		ORDER BY %s`, orderClause), boxID)
+	if err != nil {
+		return nil, err
+	}
+	var changes []SerialChange
+	for rows.Next() {
+		var c SerialChange
+		if err = rows.Scan(&c.BagID, &c.SetID, &c.SetName, &c.OldSerial); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		changes = append(changes, c)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if orderClause == "b.serial_no" {
+		// Compare running numbers, not text, so "10" sorts after "9".
+		sort.SliceStable(changes, func(i, j int) bool {
+			ni, okI := scheme.number(changes[i].OldSerial)
+			nj, okJ := scheme.number(changes[j].OldSerial)
+			if okI != okJ {
+				return okI
+			}
+			return okI && ni < nj
+		})
+	}
+
+	// Park every bag on a temporary serial first so the final assignment
+	// never collides with UNIQUE(box_id, serial_no).
+	if _, err = tx.Exec(`UPDATE bags SET serial_no = '~' || id WHERE box_id = ?`, boxID); err != nil {
+		return nil, err
+	}
+	for i := range changes {
+		changes[i].NewSerial = scheme.format(i + 1)
+		if _, err = tx.Exec(`UPDATE bags SET serial_no = ? WHERE id = ?`, changes[i].NewSerial, changes[i].BagID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	var setIDs []int64
+	for _, c := range changes {
+		if c.SetID > 0 {
+			setIDs = append(setIDs, c.SetID)
+		}
+	}
+	a.emitChange(EventSetUpdated, setIDs...)
+	return changes, nil
+}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ScannerDevice is a scanner reported by a backend.
+type ScannerDevice struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Backend   string `json:"backend"`
+	HasFeeder bool   `json:"hasFeeder"`
+}
+
+// ScanOptions controls a single scan. An empty DeviceID uses the first scanner found.
+// Color is "color" or "gray"; Feeder scans every page in the document feeder.
+// Area limits the scan to part of the glass; nil scans everything.
+// Format is "png" or "jpeg" for the saved pages.
+type ScanOptions struct {
+	DeviceID string    `json:"deviceId"`
+	DPI      int       `json:"dpi"`
+	Color    string    `json:"color"`
+	Feeder   bool      `json:"feeder"`
+	Area     *ScanArea `json:"area"`
+	Format   string    `json:"format"`
+}
+
+// ScanSettings are the saved defaults for scans started from the UI.
+type ScanSettings struct {
+	DeviceID string    `json:"deviceId"`
+	DPI      int       `json:"dpi"`
+	Color    string    `json:"color"`
+	Format   string    `json:"format"`
+	Area     *ScanArea `json:"area"`
+	AutoCrop bool      `json:"autoCrop"`
+}
+
+const (
+	settingScanDeviceID = "scan.device_id"
+	settingScanDPI      = "scan.dpi"
+	settingScanColor    = "scan.color"
+	settingScanFormat   = "scan.format"
+	settingScanArea     = "scan.area"
+	settingScanAutoCrop = "scan.auto_crop"
+)
+
+// ScanArea is a rectangle on the scanner glass in millimetres from the top left corner.
+type ScanArea struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// pixels converts the area to pixels at the given resolution.
+func (r ScanArea) pixels(dpi int) (x, y, w, h int) {
+	px := func(mm float64) int { return int(mm / 25.4 * float64(dpi)) }
+	return px(r.X), px(r.Y), px(r.Width), px(r.Height)
+}
+
+// Scanner is a platform scanning backend. Scan writes one PNG (or, for the
+// file backend, the source format) per page into dir and returns their paths in page order.
+type Scanner interface {
+	Name() string
+	Devices(ctx context.Context) ([]ScannerDevice, error)
+	Scan(ctx context.Context, opts ScanOptions, dir string) ([]string, error)
+}
+
+var errNoScanner = errors.New("no scanner found - please connect a scanner via USB")
+
+func (o *ScanOptions) validate() error {
+	if o.DPI == 0 {
+		o.DPI = 200
+	}
+	if o.DPI < 50 || o.DPI > 1200 {
+		return errors.New("scan resolution must be between 50 and 1200 DPI")
+	}
+	switch o.Color {
+	case "":
+		o.Color = "color"
+	case "color", "gray":
+	default:
+		return fmt.Errorf("unknown colour mode %q", o.Color)
+	}
+	if o.Area != nil && (o.Area.X < 0 || o.Area.Y < 0 || o.Area.Width <= 0 || o.Area.Height <= 0) {
+		return errors.New("scan area must have a positive width and height")
+	}
+	switch o.Format {
+	case "":
+		o.Format = "png"
+	case "png", "jpeg":
+	default:
+		return fmt.Errorf("unknown scan format %q", o.Format)
+	}
+	return nil
+}
+
+func readScanSettings(q queryer) (ScanSettings, error) {
+	defaults := ScanSettings{DPI: 200, Color: "color", Format: "png", AutoCrop: true}
+	settings := defaults
+	values := map[string]string{}
+	for _, key := range []string{settingScanDeviceID, settingScanDPI, settingScanColor, settingScanFormat, settingScanArea, settingScanAutoCrop} {
+		val, err := readSetting(q, key, "")
+		if err != nil {
+			return settings, err
+		}
+		values[key] = val
+	}
+	settings.DeviceID = values[settingScanDeviceID]
+	if n, err := strconv.Atoi(values[settingScanDPI]); err == nil {
+		settings.DPI = n
+	}
+	if v := values[settingScanColor]; v != "" {
+		settings.Color = v
+	}
+	if v := values[settingScanFormat]; v != "" {
+		settings.Format = v
+	}
+	if v := values[settingScanArea]; v != "" {
+		var area ScanArea
+		if err := json.Unmarshal([]byte(v), &area); err == nil {
+			settings.Area = &area
+		}
+	}
+	if v, err := strconv.ParseBool(values[settingScanAutoCrop]); err == nil {
+		settings.AutoCrop = v
+	}
+	if err := validateScanSettings(&settings); err != nil {
+		return defaults, nil
+	}
+	return settings, nil
+}
+
+// options turns the saved settings into options for a single-page scan.
+func (s ScanSettings) options() ScanOptions {
+	return ScanOptions{DeviceID: s.DeviceID, DPI: s.DPI, Color: s.Color, Area: s.Area, Format: s.Format}
+}
+
+func (a *App) GetScanSettings() (ScanSettings, error) {
+	return readScanSettings(a.db)
+}
+
+// UpdateScanSettings stores the preferred scanner, resolution, colour mode,
+// output format, scan area and auto-crop choice.
+func (a *App) UpdateScanSettings(settings ScanSettings) error {
+	_, err := a.updateSettings(func(s *Settings) {
+		s.Scan = settings
+	})
+	return err
+}
+
+func validateScanSettings(settings *ScanSettings) error {
+	opts := settings.options()
+	if err := opts.validate(); err != nil {
+		return err
+	}
+	settings.DeviceID = strings.TrimSpace(opts.DeviceID)
+	settings.DPI, settings.Color, settings.Format = opts.DPI, opts.Color, opts.Format
+	return nil
+}
+
+func writeScanSettings(q queryer, settings ScanSettings) error {
+	area := ""
+	if settings.Area != nil {
+		buf, err := json.Marshal(settings.Area)
+		if err != nil {
+			return err
+		}
+		area = string(buf)
+	}
+	values := [][2]string{
+		{settingScanDeviceID, settings.DeviceID},
+		{settingScanDPI, strconv.Itoa(settings.DPI)},
+		{settingScanColor, settings.Color},
+		{settingScanFormat, settings.Format},
+		{settingScanArea, area},
+		{settingScanAutoCrop, strconv.FormatBool(settings.AutoCrop)},
+	}
+	for _, kv := range values {
+		if err := writeSetting(q, kv[0], kv[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newScanner picks the backend for this platform. Setting SAMLA_FAKE_SCANNER_DIR
+// to a folder of images replaces the hardware with a file-based scanner.
+func newScanner() Scanner {
+	if dir := os.Getenv("SAMLA_FAKE_SCANNER_DIR"); dir != "" {
+		return fileScanner{dir: dir}
+	}
+	if runtime.GOOS == "windows" {
+		return wiaScanner{}
+	}
+	return saneScanner{}
+}
+
+func (a *App) scanContext() context.Context {
+	if a.ctx != nil {
+		return a.ctx
+	}
+	return context.Background()
+}
+
+// ListScanners returns the scanners the current backend can see.
+func (a *App) ListScanners() ([]ScannerDevice, error) {
+	return newScanner().Devices(a.scanContext())
+}
+
+// ScanPages scans one page, or every page in the feeder, into the staging
+// folder and returns a scan token per page for AttachScannedImage.
+func (a *App) ScanPages(opts ScanOptions) ([]string, error) {
+	paths := a.paths()
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	if _, err := cleanupScans(paths.ScansDir, scanStagingTTL); err != nil {
+		return nil, err
+	}
+	workDir, err := os.MkdirTemp(paths.ScansDir, "work-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(workDir)
+
+	pages, err := newScanner().Scan(a.scanContext(), opts, workDir)
+	if err != nil {
+		return nil, err
+	}
+	if len(pages) == 0 {
+		return nil, errors.New("scan failed - no image created")
+	}
+
+	var tokens []string
+	for _, page := range pages {
+		if opts.Format == "jpeg" {
+			if page, err = a.convertScanToJPEG(page); err != nil {
+				return tokens, err
+			}
+		}
+		token := newScanToken(filepath.Ext(page))
+		if err := os.Rename(page, filepath.Join(paths.ScansDir, token)); err != nil {
+			return tokens, err
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, nil
+}
+
+// convertScanToJPEG re-encodes a scanned page with the configured JPEG quality.
+func (a *App) convertScanToJPEG(page string) (string, error) {
+	img, err := decodeScan(page)
+	if err != nil {
+		return "", err
+	}
+	out := strings.TrimSuffix(page, filepath.Ext(page)) + ".jpg"
+	if err := a.writeScanImage(out, img, "jpeg"); err != nil {
+		return "", err
+	}
+	if out != page {
+		_ = os.Remove(page)
+	}
+	return out, nil
+}
+
+func decodeScan(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read scan: %w", err)
+	}
+	return img, nil
+}
+
+// writeScanImage saves img to path as PNG or, for format "jpeg", as JPEG
+// with the configured quality.
+func (a *App) writeScanImage(path string, img image.Image, format string) error {
+	quality := 0
+	if format == "jpeg" {
+		settings, err := readImageSettings(a.db)
+		if err != nil {
+			return err
+		}
+		quality = settings.JPEGQuality
+	}
+	dst, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if quality > 0 {
+		err = jpeg.Encode(dst, img, &jpeg.Options{Quality: quality})
+	} else {
+		err = png.Encode(dst, img)
+	}
+	if err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+// scannedPages lists the page files a backend left in dir, sorted by name.
+func scannedPages(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var pages []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasPrefix(e.Name(), "page_") {
+			pages = append(pages, filepath.Join(dir, e.Name()))
+		}
+	}
+	sort.Strings(pages)
+	return pages, nil
+}
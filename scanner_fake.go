@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// fileScanner pretends to scan by copying image files from a folder. It is
+// used for development and tests on machines without a scanner: one page
+// is the first file by name, a feeder scan returns every file. Resolution,
+// colour mode and area are ignored.
+type fileScanner struct {
+	dir string
+}
+
+func (fileScanner) Name() string { return "file" }
+
+func (s fileScanner) Devices(ctx context.Context) ([]ScannerDevice, error) {
+	if info, err := os.Stat(s.dir); err != nil || !info.IsDir() {
+		return nil, nil
+	}
+	return []ScannerDevice{{ID: "file", Name: "File scanner (" + s.dir + ")", Backend: "file", HasFeeder: true}}, nil
+}
+
+func (s fileScanner) Scan(ctx context.Context, opts ScanOptions, dir string) ([]string, error) {
+	if opts.DeviceID != "" && opts.DeviceID != "file" {
+		return nil, errNoScanner
+	}
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, errNoScanner
+	}
+	var sources []string
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		switch strings.ToLower(filepath.Ext(e.Name())) {
+		case ".png", ".jpg", ".jpeg", ".gif", ".bmp", ".webp":
+			sources = append(sources, filepath.Join(s.dir, e.Name()))
+		}
+	}
+	if len(sources) == 0 {
+		return nil, errNoScanner
+	}
+	sort.Strings(sources)
+	if !opts.Feeder {
+		sources = sources[:1]
+	}
+
+	var pages []string
+	for i, src := range sources {
+		page := filepath.Join(dir, fmt.Sprintf("page_%03d%s", i+1, strings.ToLower(filepath.Ext(src))))
+		if err := copyFile(src, page); err != nil {
+			return nil, err
+		}
+		pages = append(pages, page)
+	}
+	return pages, nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// saneScanner uses the scanimage command line tool from SANE.
+type saneScanner struct{}
+
+func (saneScanner) Name() string { return "sane" }
+
+var errNoScanimage = errors.New("scanimage not found - please install SANE (sane-utils)")
+
+func (saneScanner) Devices(ctx context.Context) ([]ScannerDevice, error) {
+	out, err := runScanimage(ctx, "--formatted-device-list=%d|%v %m%n")
+	if err != nil {
+		return nil, err
+	}
+	var devices []ScannerDevice
+	for _, line := range strings.Split(string(out), "\n") {
+		id, name, ok := strings.Cut(strings.TrimSpace(line), "|")
+		if !ok || id == "" {
+			continue
+		}
+		devices = append(devices, ScannerDevice{ID: id, Name: strings.TrimSpace(name), Backend: "sane"})
+	}
+	return devices, nil
+}
+
+func (s saneScanner) Scan(ctx context.Context, opts ScanOptions, dir string) ([]string, error) {
+	deviceID := opts.DeviceID
+	if deviceID == "" {
+		devices, err := s.Devices(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if len(devices) == 0 {
+			return nil, errNoScanner
+		}
+		deviceID = devices[0].ID
+	}
+
+	mode := "Color"
+	if opts.Color == "gray" {
+		mode = "Gray"
+	}
+	args := []string{"-d", deviceID, "--resolution", strconv.Itoa(opts.DPI), "--mode", mode, "--format=png"}
+	if a := opts.Area; a != nil {
+		mm := func(v float64) string { return strconv.FormatFloat(v, 'f', 1, 64) }
+		args = append(args, "-l", mm(a.X), "-t", mm(a.Y), "-x", mm(a.Width), "-y", mm(a.Height))
+	}
+
+	if !opts.Feeder {
+		out, err := runScanimage(ctx, args...)
+		if err != nil {
+			return nil, err
+		}
+		page := filepath.Join(dir, "page_001.png")
+		if err := os.WriteFile(page, out, 0o644); err != nil {
+			return nil, err
+		}
+		return []string{page}, nil
+	}
+
+	args = append(args, "--source", "ADF", "--batch="+filepath.Join(dir, "page_%03d.png"))
+	_, err := runScanimage(ctx, args...)
+	pages, listErr := scannedPages(dir)
+	if listErr != nil {
+		return nil, listErr
+	}
+	// scanimage reports an empty feeder as an error once the batch is done.
+	if err != nil && len(pages) == 0 {
+		return nil, err
+	}
+	return pages, nil
+}
+
+func runScanimage(ctx context.Context, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "scanimage", args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return nil, errNoScanimage
+		}
+		msg := strings.TrimSpace(stderr.String())
+		if strings.Contains(msg, "no SANE devices found") || strings.Contains(msg, "open of device") {
+			return nil, errNoScanner
+		}
+		if msg != "" {
+			return nil, fmt.Errorf("scan failed: %s", msg)
+		}
+		return nil, fmt.Errorf("scan failed: %w", err)
+	}
+	return stdout.Bytes(), nil
+}
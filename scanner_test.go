@@ -0,0 +1,178 @@
+package main
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// writeTestPNG writes a plain image of the given size to path.
+func writeTestPNG(t *testing.T, path string, w, h int) {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// fakeScannerDir fills a folder with pages of different widths, so the page
+// order can be told from the scans, and points the scanner at it.
+func fakeScannerDir(t *testing.T, widths ...int) string {
+	t.Helper()
+	dir := t.TempDir()
+	for i, w := range widths {
+		writeTestPNG(t, filepath.Join(dir, string(rune('a'+i))+".png"), w, 10)
+	}
+	// Files the scanner must skip.
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SAMLA_FAKE_SCANNER_DIR", dir)
+	return dir
+}
+
+func scanWidth(t *testing.T, a *App, token string) int {
+	t.Helper()
+	path, err := a.stagedScanPath(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := decodeScan(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return img.Bounds().Dx()
+}
+
+func TestNewScannerBackend(t *testing.T) {
+	t.Setenv("SAMLA_FAKE_SCANNER_DIR", "")
+	want := "sane"
+	if runtime.GOOS == "windows" {
+		want = "wia"
+	}
+	if got := newScanner().Name(); got != want {
+		t.Errorf("without a fake folder: got %q, want %q", got, want)
+	}
+
+	t.Setenv("SAMLA_FAKE_SCANNER_DIR", t.TempDir())
+	if got := newScanner().Name(); got != "file" {
+		t.Errorf("with a fake folder: got %q, want file", got)
+	}
+}
+
+func TestListScannersFake(t *testing.T) {
+	a := newTestApp(t)
+	fakeScannerDir(t, 20)
+	devices, err := a.ListScanners()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(devices) != 1 || devices[0].ID != "file" || devices[0].Backend != "file" || !devices[0].HasFeeder {
+		t.Fatalf("devices: %+v", devices)
+	}
+
+	t.Setenv("SAMLA_FAKE_SCANNER_DIR", filepath.Join(t.TempDir(), "missing"))
+	devices, err = a.ListScanners()
+	if err != nil || len(devices) != 0 {
+		t.Fatalf("missing folder: %+v, %v", devices, err)
+	}
+}
+
+func TestScanPagesFake(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   ScanOptions
+		widths []int
+		ext    string
+	}{
+		{"single page", ScanOptions{}, []int{20}, ".png"},
+		{"feeder", ScanOptions{Feeder: true}, []int{20, 30, 40}, ".png"},
+		{"jpeg", ScanOptions{Format: "jpeg", Feeder: true}, []int{20, 30, 40}, ".jpg"},
+		{"named device", ScanOptions{DeviceID: "file"}, []int{20}, ".png"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestApp(t)
+			fakeScannerDir(t, 20, 30, 40)
+			tokens, err := a.ScanPages(tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(tokens) != len(tt.widths) {
+				t.Fatalf("got %d pages, want %d", len(tokens), len(tt.widths))
+			}
+			for i, token := range tokens {
+				if !strings.HasSuffix(token, tt.ext) {
+					t.Errorf("page %d: token %q does not end in %s", i+1, token, tt.ext)
+				}
+				if w := scanWidth(t, a, token); w != tt.widths[i] {
+					t.Errorf("page %d: width %d, want %d", i+1, w, tt.widths[i])
+				}
+			}
+
+			// Only the staged pages are left; the work folder is gone.
+			entries, err := os.ReadDir(a.paths().ScansDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != len(tokens) {
+				t.Errorf("staging folder holds %d entries, want %d", len(entries), len(tokens))
+			}
+		})
+	}
+}
+
+func TestScanPagesNoDevice(t *testing.T) {
+	a := newTestApp(t)
+
+	// A folder without images is a scanner without paper.
+	empty := t.TempDir()
+	t.Setenv("SAMLA_FAKE_SCANNER_DIR", empty)
+	if _, err := a.ScanPages(ScanOptions{}); !errors.Is(err, errNoScanner) {
+		t.Errorf("empty folder: %v", err)
+	}
+
+	fakeScannerDir(t, 20)
+	if _, err := a.ScanPages(ScanOptions{DeviceID: "other"}); !errors.Is(err, errNoScanner) {
+		t.Errorf("unknown device: %v", err)
+	}
+
+	t.Setenv("SAMLA_FAKE_SCANNER_DIR", filepath.Join(empty, "missing"))
+	if _, err := a.ScanPages(ScanOptions{}); !errors.Is(err, errNoScanner) {
+		t.Errorf("missing folder: %v", err)
+	}
+}
+
+func TestScanPagesRejectsInvalidOptions(t *testing.T) {
+	a := newTestApp(t)
+	fakeScannerDir(t, 20)
+	for _, opts := range []ScanOptions{
+		{DPI: 10},
+		{Color: "sepia"},
+		{Format: "tiff"},
+		{Area: &ScanArea{Width: 0, Height: 10}},
+	} {
+		if _, err := a.ScanPages(opts); err == nil {
+			t.Errorf("%+v was accepted", opts)
+		}
+	}
+}
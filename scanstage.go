@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// scanStagingTTL is how long an unattached scan is kept in the staging folder.
+const scanStagingTTL = time.Hour
+
+var errInvalidScanToken = errors.New("invalid scan token")
+
+// newScanToken names a staged scan. The token is the file name inside
+// ScansDir, so it never contains a path.
+func newScanToken(ext string) string {
+	return fmt.Sprintf("scan_%s%s", uuid.NewString(), strings.ToLower(ext))
+}
+
+// stagedScanPath resolves a scan token to its file in the staging folder.
+// Only tokens handed out by newScanToken are accepted.
+func (a *App) stagedScanPath(token string) (string, error) {
+	name, ext, ok := strings.Cut(strings.TrimPrefix(token, "scan_"), ".")
+	if !ok || !strings.HasPrefix(token, "scan_") || filepath.Base(token) != token {
+		return "", errInvalidScanToken
+	}
+	if _, err := uuid.Parse(name); err != nil {
+		return "", errInvalidScanToken
+	}
+	switch ext {
+	case "png", "jpg", "jpeg", "gif", "bmp", "webp":
+	default:
+		return "", errInvalidScanToken
+	}
+	return filepath.Join(a.paths().ScansDir, token), nil
+}
+
+// cleanupScans removes staged scans in scansDir, and leftovers of interrupted
+// scans, older than maxAge. A maxAge of 0 empties the staging folder.
+func cleanupScans(scansDir string, maxAge time.Duration) (int, error) {
+	if scansDir == "" {
+		return 0, nil
+	}
+	entries, err := os.ReadDir(scansDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	cutoff := time.Now().Add(-maxAge)
+	removed := 0
+	for _, e := range entries {
+		if maxAge > 0 {
+			info, err := e.Info()
+			if err != nil || info.ModTime().After(cutoff) {
+				continue
+			}
+		}
+		if err := os.RemoveAll(filepath.Join(scansDir, e.Name())); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestStagedScanPath(t *testing.T) {
+	a := newTestApp(t)
+	id := uuid.NewString()
+	tests := []struct {
+		name  string
+		token string
+		ok    bool
+	}{
+		{"new token", newScanToken(".PNG"), true},
+		{"jpeg", "scan_" + id + ".jpeg", true},
+		{"empty", "", false},
+		{"parent folder", "../scan_" + id + ".png", false},
+		{"parent folder inside", "scan_" + id + ".png/../../samla.db", false},
+		{"nested", "sub/scan_" + id + ".png", false},
+		{"backslash", `..\scan_` + id + ".png", false},
+		{"absolute", filepath.Join(os.TempDir(), "scan_"+id+".png"), false},
+		{"not a uuid", "scan_holiday.png", false},
+		{"uuid with traversal", "scan_../" + id + ".png", false},
+		{"wrong prefix", "photo_" + id + ".png", false},
+		{"no prefix", id + ".png", false},
+		{"wrong extension", "scan_" + id + ".exe", false},
+		{"double extension", "scan_" + id + ".png.exe", false},
+		{"upper-case extension", "scan_" + id + ".PNG", false},
+		{"no extension", "scan_" + id, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, err := a.stagedScanPath(tt.token)
+			if !tt.ok {
+				if !errors.Is(err, errInvalidScanToken) {
+					t.Fatalf("%q: got %q, %v; want errInvalidScanToken", tt.token, path, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("%q: %v", tt.token, err)
+			}
+			if filepath.Dir(path) != a.paths().ScansDir {
+				t.Fatalf("%q resolved outside the staging folder: %s", tt.token, path)
+			}
+		})
+	}
+}
+
+func TestAttachScannedImage(t *testing.T) {
+	a := newTestApp(t)
+	setID := addTestSet(t, a, "scanned")
+
+	// A real file outside the staging folder must not be reachable.
+	outside := filepath.Join(a.paths().DataDir, "scan_"+uuid.NewString()+".png")
+	writeTestPNG(t, outside, 20, 10)
+	rel, err := filepath.Rel(a.paths().ScansDir, outside)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.AttachScannedImage(setID, rel); !errors.Is(err, errInvalidScanToken) {
+		t.Fatalf("token %q: %v", rel, err)
+	}
+	if _, err := a.AttachScannedImage(setID, outside); !errors.Is(err, errInvalidScanToken) {
+		t.Fatalf("absolute token: %v", err)
+	}
+
+	if _, err := a.AttachScannedImage(setID, newScanToken(".png")); err == nil || !strings.Contains(err.Error(), "expired") {
+		t.Fatalf("missing scan: %v", err)
+	}
+
+	token := newScanToken(".png")
+	writeTestPNG(t, filepath.Join(a.paths().ScansDir, token), 20, 10)
+	relPath, err := a.AttachScannedImage(setID, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(a.paths().BaseDir, filepath.FromSlash(relPath))); err != nil {
+		t.Fatalf("attached image: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(a.paths().ScansDir, token)); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("staged scan was kept after attaching: %v", err)
+	}
+}
+
+func TestCleanupScans(t *testing.T) {
+	dir := t.TempDir()
+	old := time.Now().Add(-2 * scanStagingTTL)
+	stale := []string{newScanToken(".png"), "work-123"}
+	fresh := []string{newScanToken(".jpg"), "work-456"}
+	for _, name := range append(append([]string{}, stale...), fresh...) {
+		p := filepath.Join(dir, name)
+		var err error
+		if strings.HasPrefix(name, "work-") {
+			err = os.Mkdir(p, 0o755)
+		} else {
+			err = os.WriteFile(p, []byte("x"), 0o644)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range stale {
+		if err := os.Chtimes(filepath.Join(dir, name), old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	n, err := cleanupScans(dir, scanStagingTTL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(stale) {
+		t.Errorf("removed %d, want %d", n, len(stale))
+	}
+	for _, name := range stale {
+		if _, err := os.Stat(filepath.Join(dir, name)); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("expired %s was kept", name)
+		}
+	}
+	for _, name := range fresh {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("fresh %s was removed: %v", name, err)
+		}
+	}
+
+	if n, err := cleanupScans(dir, 0); err != nil || n != len(fresh) {
+		t.Errorf("emptying the folder removed %d: %v", n, err)
+	}
+	if n, err := cleanupScans(filepath.Join(dir, "missing"), 0); err != nil || n != 0 {
+		t.Errorf("missing folder: %d, %v", n, err)
+	}
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	settingSerialTemplate  = "serial.template"
+	settingSerialReuseGaps = "serial.reuse_gaps"
+	defaultSerialTemplate  = "{n:04}"
+)
+
+// SerialSettings holds the global bag serial numbering scheme.
+type SerialSettings struct {
+	Template  string `json:"template"`
+	ReuseGaps bool   `json:"reuseGaps"`
+}
+
+// serialScheme is a template resolved for one box: serials look like prefix + number + suffix.
+type serialScheme struct {
+	prefix string
+	suffix string
+	width  int
+}
+
+var serialTokenRe = regexp.MustCompile(`\{([a-z]+)(?::(\d+))?\}`)
+
+// parseSerialTemplate resolves a template such as "{box}-{n:03}" or "{year}/{n}".
+// Supported tokens: {n} or {n:WIDTH} (zero-padded), {box}, {year}, {yy}.
+func parseSerialTemplate(tmpl, boxCode string, now time.Time) (serialScheme, error) {
+	var scheme serialScheme
+	var out strings.Builder
+	numbers := 0
+	last := 0
+	for _, m := range serialTokenRe.FindAllStringSubmatchIndex(tmpl, -1) {
+		out.WriteString(tmpl[last:m[0]])
+		last = m[1]
+
+		name := tmpl[m[2]:m[3]]
+		hasWidth := m[4] >= 0
+		switch name {
+		case "n":
+			numbers++
+			if numbers > 1 {
+				return scheme, errors.New("serial template must contain {n} only once")
+			}
+			if hasWidth {
+				w, err := strconv.Atoi(tmpl[m[4]:m[5]])
+				if err != nil || w > 12 {
+					return scheme, fmt.Errorf("invalid width in %s", tmpl[m[0]:m[1]])
+				}
+				scheme.width = w
+			}
+			scheme.prefix = out.String()
+			out.Reset()
+			continue
+		case "box":
+			out.WriteString(boxCode)
+		case "year":
+			out.WriteString(strconv.Itoa(now.Year()))
+		case "yy":
+			out.WriteString(fmt.Sprintf("%02d", now.Year()%100))
+		default:
+			return scheme, fmt.Errorf("unknown serial template token {%s}", name)
+		}
+		if hasWidth {
+			return scheme, fmt.Errorf("token {%s} does not take a width", name)
+		}
+	}
+	out.WriteString(tmpl[last:])
+
+	if numbers == 0 {
+		return scheme, errors.New("serial template must contain {n}")
+	}
+	if strings.ContainsAny(scheme.prefix+out.String(), "{}") {
+		return scheme, errors.New("serial template contains an unmatched brace")
+	}
+	scheme.suffix = out.String()
+	return scheme, nil
+}
+
+func (s serialScheme) format(n int) string {
+	return fmt.Sprintf("%s%0*d%s", s.prefix, s.width, n, s.suffix)
+}
+
+// number extracts the running number from a serial produced by this scheme.
+func (s serialScheme) number(serial string) (int, bool) {
+	if len(serial) <= len(s.prefix)+len(s.suffix) ||
+		!strings.HasPrefix(serial, s.prefix) || !strings.HasSuffix(serial, s.suffix) {
+		return 0, false
+	}
+	mid := serial[len(s.prefix) : len(serial)-len(s.suffix)]
+	for _, r := range mid {
+		if r < '0' || r > '9' {
+			return 0, false
+		}
+	}
+	n, err := strconv.Atoi(mid)
+	return n, err == nil
+}
+
+func readSerialSettings(q queryer) (SerialSettings, error) {
+	defaults := SerialSettings{Template: defaultSerialTemplate}
+	settings := defaults
+	tmpl, err := readSetting(q, settingSerialTemplate, defaultSerialTemplate)
+	if err != nil {
+		return settings, err
+	}
+	reuse, err := readSetting(q, settingSerialReuseGaps, "false")
+	if err != nil {
+		return settings, err
+	}
+	settings.Template = tmpl
+	settings.ReuseGaps, _ = strconv.ParseBool(reuse)
+	if err := validateSerialSettings(&settings); err != nil {
+		return defaults, nil
+	}
+	return settings, nil
+}
+
+// boxSerialScheme resolves the serial template for a box, falling back to the global one.
+func boxSerialScheme(q queryer, boxID int64) (serialScheme, SerialSettings, error) {
+	settings, err := readSerialSettings(q)
+	if err != nil {
+		return serialScheme{}, settings, err
+	}
+	var code, tmpl string
+	err = q.QueryRow(`SELECT code, IFNULL(serial_template,'') FROM boxes WHERE id = ?`, boxID).Scan(&code, &tmpl)
+	if errors.Is(err, sql.ErrNoRows) {
+		return serialScheme{}, settings, errors.New("box not found")
+	}
+	if err != nil {
+		return serialScheme{}, settings, err
+	}
+	if tmpl == "" {
+		tmpl = settings.Template
+	}
+	scheme, err := parseSerialTemplate(tmpl, code, time.Now())
+	return scheme, settings, err
+}
+
+// nextBagSerial allocates the next free serial in a box. Run it on the same
+// transaction that inserts the bag so the result cannot be taken in between.
+func nextBagSerial(q queryer, boxID int64) (string, error) {
+	if boxID <= 0 {
+		return "", errors.New("box is required")
+	}
+	scheme, settings, err := boxSerialScheme(q, boxID)
+	if err != nil {
+		return "", err
+	}
+
+	rows, err := q.Query(`SELECT serial_no FROM bags WHERE box_id = ?`, boxID)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	taken := make(map[string]bool)
+	used := make(map[int]bool)
+	highest := 0
+	for rows.Next() {
+		var serial string
+		if err := rows.Scan(&serial); err != nil {
+			return "", err
+		}
+		taken[serial] = true
+		if n, ok := scheme.number(serial); ok {
+			used[n] = true
+			if n > highest {
+				highest = n
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	n := highest + 1
+	if settings.ReuseGaps {
+		n = 1
+		for used[n] {
+			n++
+		}
+	}
+	// A hand-entered serial may still match the rendered value, e.g. "7" vs "07".
+	for taken[scheme.format(n)] {
+		n++
+	}
+	return scheme.format(n), nil
+}
+
+func (a *App) GetSerialSettings() (SerialSettings, error) {
+	return readSerialSettings(a.db)
+}
+
+// UpdateSerialSettings stores the global serial template and gap reuse option.
+func (a *App) UpdateSerialSettings(settings SerialSettings) error {
+	_, err := a.updateSettings(func(s *Settings) {
+		s.Serial = settings
+	})
+	return err
+}
+
+func validateSerialSettings(settings *SerialSettings) error {
+	settings.Template = strings.TrimSpace(settings.Template)
+	if settings.Template == "" {
+		settings.Template = defaultSerialTemplate
+	}
+	_, err := parseSerialTemplate(settings.Template, "BOX", time.Now())
+	return err
+}
+
+func writeSerialSettings(q queryer, settings SerialSettings) error {
+	if err := writeSetting(q, settingSerialTemplate, settings.Template); err != nil {
+		return err
+	}
+	return writeSetting(q, settingSerialReuseGaps, strconv.FormatBool(settings.ReuseGaps))
+}
+
+// SetBoxSerialTemplate overrides the serial template for one box. An empty template uses the global one.
+func (a *App) SetBoxSerialTemplate(boxID int64, template string) error {
+	template = strings.TrimSpace(template)
+	if template != "" {
+		if _, err := parseSerialTemplate(template, "BOX", time.Now()); err != nil {
+			return err
+		}
+	}
+	res, err := a.db.Exec(`UPDATE boxes SET serial_template = NULLIF(?, '') WHERE id = ?`, template, boxID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return errors.New("box not found")
+	}
+	a.emitChange(EventBoxUpdated, boxID)
+	return nil
+}
+
+// PreviewSerialTemplate renders the first three serials a template would produce for a box code.
+func (a *App) PreviewSerialTemplate(template, boxCode string) ([]string, error) {
+	scheme, err := parseSerialTemplate(strings.TrimSpace(template), boxCode, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return []string{scheme.format(1), scheme.format(2), scheme.format(3)}, nil
+}
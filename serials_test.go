@@ -0,0 +1,214 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSerialTemplate(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		tmpl string
+		box  string
+		want string // the serial for number 7
+	}{
+		{"{n}", "A", "7"},
+		{"{n:04}", "A", "0007"},
+		{"{n:4}", "A", "0007"},
+		{"{n:0}", "A", "7"},
+		{"{box}-{n:03}", "A1", "A1-007"},
+		{"{year}/{n}", "A", "2024/7"},
+		{"{yy}{n:02}", "A", "2407"},
+		{"S{n:2}X", "A", "S07X"},
+		{"{box}-{n}-{yy}", "K", "K-7-24"},
+	}
+	for _, tt := range tests {
+		scheme, err := parseSerialTemplate(tt.tmpl, tt.box, now)
+		if err != nil {
+			t.Errorf("%s: %v", tt.tmpl, err)
+			continue
+		}
+		if got := scheme.format(7); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.tmpl, got, tt.want)
+		}
+		if n, ok := scheme.number(tt.want); !ok || n != 7 {
+			t.Errorf("%s: number(%q) = %d, %v", tt.tmpl, tt.want, n, ok)
+		}
+	}
+}
+
+func TestParseSerialTemplateInvalid(t *testing.T) {
+	for _, tmpl := range []string{
+		"",
+		"plain",
+		"{box}",
+		"{n}{n}",
+		"{n:13}",
+		"{box:2}-{n}",
+		"{year:4}/{n}",
+		"{foo}-{n}",
+		"{N}",
+		"{n",
+		"{n}}",
+		"A{-{n}",
+	} {
+		if _, err := parseSerialTemplate(tmpl, "A", time.Now()); err == nil {
+			t.Errorf("%q was accepted", tmpl)
+		}
+	}
+}
+
+func TestSerialSchemeNumber(t *testing.T) {
+	scheme, err := parseSerialTemplate("{box}-{n:03}", "B", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		serial string
+		n      int
+		ok     bool
+	}{
+		{"B-012", 12, true},
+		{"B-0001", 1, true},
+		{"B-7", 7, true},
+		{"B-", 0, false},
+		{"B-1a", 0, false},
+		{"B--1", 0, false},
+		{"C-001", 0, false},
+		{"001", 0, false},
+	}
+	for _, tt := range tests {
+		n, ok := scheme.number(tt.serial)
+		if n != tt.n || ok != tt.ok {
+			t.Errorf("number(%q) = %d, %v; want %d, %v", tt.serial, n, ok, tt.n, tt.ok)
+		}
+	}
+}
+
+// serialTestBox creates a box holding bags with the given serials.
+func serialTestBox(t *testing.T, a *App, code string, serials ...string) int64 {
+	t.Helper()
+	loc, err := a.CreateLocation("Shelf "+code, "", "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	box, err := a.CreateBox(loc, code, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range serials {
+		if _, err := a.CreateBagWithSet(box, s, "Set "+s, "", ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return box
+}
+
+func TestNextBagSerial(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		reuse    bool
+		serials  []string
+		want     string
+	}{
+		{"empty box", "{n:04}", false, nil, "0001"},
+		{"after the highest", "{n:04}", false, []string{"0001", "0002", "0005"}, "0006"},
+		{"gap reuse off", "{n:04}", false, []string{"0001", "0003"}, "0004"},
+		{"gap reuse on", "{n:04}", true, []string{"0001", "0003"}, "0002"},
+		{"gap reuse without gaps", "{n:04}", true, []string{"0001", "0002"}, "0003"},
+		{"other serials ignored", "{n:04}", false, []string{"0002", "old-9", "X12"}, "0003"},
+		{"unpadded serial counts", "{n:02}", false, []string{"01", "7"}, "08"},
+		{"box template", "{box}-{n:02}", true, []string{"K-01", "K-03"}, "K-02"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestApp(t)
+			if err := a.UpdateSerialSettings(SerialSettings{Template: tt.template, ReuseGaps: tt.reuse}); err != nil {
+				t.Fatal(err)
+			}
+			box := serialTestBox(t, a, "K", tt.serials...)
+			got, err := a.GetNextBagSerial(box)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBoxSerialTemplate(t *testing.T) {
+	a := newTestApp(t)
+	box := serialTestBox(t, a, "K", "0001")
+	if err := a.SetBoxSerialTemplate(box, "{n"); err == nil {
+		t.Error("invalid box template was accepted")
+	}
+	if err := a.SetBoxSerialTemplate(box, "{box}/{n:03}"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := a.GetNextBagSerial(box); err != nil || got != "K/001" {
+		t.Errorf("box template: %q, %v", got, err)
+	}
+	if err := a.SetBoxSerialTemplate(box, ""); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := a.GetNextBagSerial(box); err != nil || got != "0002" {
+		t.Errorf("back to the global template: %q, %v", got, err)
+	}
+}
+
+func TestMoveBagsIntoOtherScheme(t *testing.T) {
+	a := newTestApp(t)
+	target := serialTestBox(t, a, "T", "0001", "0002")
+	source := serialTestBox(t, a, "S", "0002", "S-5")
+	if err := a.SetBoxSerialTemplate(source, "{box}-{n}"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Deleting a box moves its bags, keeping serials that are free in the target.
+	if err := a.DeleteBox(source, target, false); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := a.db.Query(`SELECT serial_no FROM bags WHERE box_id = ? ORDER BY serial_no`, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var serials []string
+	for rows.Next() {
+		var serial string
+		if err := rows.Scan(&serial); err != nil {
+			t.Fatal(err)
+		}
+		serials = append(serials, serial)
+	}
+	// Both boxes had a 0002, so the moved bag gets the next serial of the target.
+	if got := strings.Join(serials, " "); got != "0001 0002 0003 S-5" {
+		t.Fatalf("serials after moving: %s", got)
+	}
+
+	// The kept serial does not follow the target's scheme and is not counted.
+	next, err := a.GetNextBagSerial(target)
+	if err != nil || next != "0004" {
+		t.Errorf("next serial in target: %q, %v", next, err)
+	}
+
+	// A bulk move always numbers the bag in the scheme of the target box.
+	other := serialTestBox(t, a, "O")
+	if err := a.SetBoxSerialTemplate(other, "{box}{n:02}"); err != nil {
+		t.Fatal(err)
+	}
+	var setID int64
+	if err := a.db.QueryRow(`SELECT s.id FROM sets s JOIN bags b ON b.id = s.bag_id WHERE b.serial_no = 'S-5'`).Scan(&setID); err != nil {
+		t.Fatal(err)
+	}
+	results, err := a.BulkMoveSets([]int64{setID}, other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || !results[0].OK || results[0].BagSerial != "O01" {
+		t.Errorf("bulk move: %+v", results)
+	}
+}
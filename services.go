@@ -45,7 +45,12 @@ func (a *App) CreateLocation(name, room, shelf, compartment, note string) (int64
 	if err != nil {
 		return 0, err
 	}
-	return res.LastInsertId()
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	a.emitChange(EventLocationCreated, id)
+	return id, nil
 }
 
 func (a *App) UpdateLocation(id int64, name, room, shelf, compartment, note string) error {
@@ -55,22 +60,41 @@ func (a *App) UpdateLocation(id int64, name, room, shelf, compartment, note stri
 	}
 	_, err := a.db.Exec(`UPDATE storage_locations SET friendly_name = ?, room = ?, shelf = ?, compartment = ?, note = ? WHERE id = ?`,
 		name, strings.TrimSpace(room), strings.TrimSpace(shelf), strings.TrimSpace(compartment), strings.TrimSpace(note), id)
-	return err
+	if err != nil {
+		return err
+	}
+	a.emitChange(EventLocationUpdated, id)
+	return nil
 }
 
-func (a *App) DeleteLocation(id int64) error {
-	_, err := a.db.Exec(`DELETE FROM storage_locations WHERE id = ?`, id)
-	return err
+// DeleteLocation removes a location. Its boxes are moved to targetLocationID
+// when given; otherwise a location with boxes is only deleted, together with
+// everything in it, when confirmCascade is set.
+func (a *App) DeleteLocation(id, targetLocationID int64, confirmCascade bool) error {
+	release, err := a.db.hold()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	act, err := a.deleteLocation(id, targetLocationID, confirmCascade)
+	if err != nil {
+		return err
+	}
+	a.pushUndo(act)
+	a.emitChange(EventLocationDeleted, id)
+	a.emitChanges(act.changes)
+	return nil
 }
 
 // Boxes
 func (a *App) ListBoxes(locationID int64) ([]Box, error) {
-	var rows *sql.Rows
+	var rows *dbRows
 	var err error
 	if locationID > 0 {
-		rows, err = a.db.Query(`SELECT id, location_id, code, IFNULL(name,'') FROM boxes WHERE location_id = ? ORDER BY code`, locationID)
+		rows, err = a.db.Query(`SELECT id, location_id, code, IFNULL(name,''), IFNULL(serial_template,'') FROM boxes WHERE location_id = ? ORDER BY code`, locationID)
 	} else {
-		rows, err = a.db.Query(`SELECT id, location_id, code, IFNULL(name,'') FROM boxes ORDER BY code`)
+		rows, err = a.db.Query(`SELECT id, location_id, code, IFNULL(name,''), IFNULL(serial_template,'') FROM boxes ORDER BY code`)
 	}
 	if err != nil {
 		return nil, err
@@ -80,7 +104,7 @@ func (a *App) ListBoxes(locationID int64) ([]Box, error) {
 	var list []Box
 	for rows.Next() {
 		var b Box
-		if err := rows.Scan(&b.ID, &b.LocationID, &b.Code, &b.Name); err != nil {
+		if err := rows.Scan(&b.ID, &b.LocationID, &b.Code, &b.Name, &b.SerialTemplate); err != nil {
 			return nil, err
 		}
 		list = append(list, b)
@@ -100,7 +124,12 @@ func (a *App) CreateBox(locationID int64, code, name string) (int64, error) {
 	if err != nil {
 		return 0, err
 	}
-	return res.LastInsertId()
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	a.emitChange(EventBoxCreated, id)
+	return id, nil
 }
 
 func (a *App) UpdateBox(id int64, locationID int64, code, name string) error {
@@ -112,12 +141,31 @@ func (a *App) UpdateBox(id int64, locationID int64, code, name string) error {
 		return errors.New("location is required")
 	}
 	_, err := a.db.Exec(`UPDATE boxes SET location_id = ?, code = ?, name = ? WHERE id = ?`, locationID, code, strings.TrimSpace(name), id)
-	return err
+	if err != nil {
+		return err
+	}
+	a.emitChange(EventBoxUpdated, id)
+	return nil
 }
 
-func (a *App) DeleteBox(id int64) error {
-	_, err := a.db.Exec(`DELETE FROM boxes WHERE id = ?`, id)
-	return err
+// DeleteBox removes a box. Its bags are moved to targetBoxID when given;
+// otherwise a box with bags is only deleted, together with its sets, when
+// confirmCascade is set.
+func (a *App) DeleteBox(id, targetBoxID int64, confirmCascade bool) error {
+	release, err := a.db.hold()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	act, err := a.deleteBox(id, targetBoxID, confirmCascade)
+	if err != nil {
+		return err
+	}
+	a.pushUndo(act)
+	a.emitChange(EventBoxDeleted, id)
+	a.emitChanges(act.changes)
+	return nil
 }
 
 // Manufacturers
@@ -177,17 +225,21 @@ func (a *App) UpdateManufacturer(id int64, name string) error {
 	if name == "" {
 		return errors.New("name is required")
 	}
-	_, err := a.db.Exec(`UPDATE manufacturers SET name = ? WHERE id = ?`, name, id)
-	return err
+	return a.updateLinkedSets(`SELECT id FROM sets WHERE manufacturer_id = ?`, id, func(tx *dbTx) error {
+		_, err := tx.Exec(`UPDATE manufacturers SET name = ? WHERE id = ?`, name, id)
+		return err
+	})
 }
 
 func (a *App) DeleteManufacturer(id int64) error {
-	// Set manufacturer_id to NULL for all sets using this manufacturer
-	if _, err := a.db.Exec(`UPDATE sets SET manufacturer_id = NULL WHERE manufacturer_id = ?`, id); err != nil {
+	return a.updateLinkedSets(`SELECT id FROM sets WHERE manufacturer_id = ?`, id, func(tx *dbTx) error {
+		// Set manufacturer_id to NULL for all sets using this manufacturer
+		if _, err := tx.Exec(`UPDATE sets SET manufacturer_id = NULL WHERE manufacturer_id = ?`, id); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`DELETE FROM manufacturers WHERE id = ?`, id)
 		return err
-	}
-	_, err := a.db.Exec(`DELETE FROM manufacturers WHERE id = ?`, id)
-	return err
+	})
 }
 
 // Types
@@ -245,44 +297,61 @@ func (a *App) UpdateType(id int64, name string) error {
 	if name == "" {
 		return errors.New("name is required")
 	}
-	_, err := a.db.Exec(`UPDATE types SET name = ? WHERE id = ?`, name, id)
-	return err
+	return a.updateLinkedSets(`SELECT id FROM sets WHERE type_id = ?`, id, func(tx *dbTx) error {
+		_, err := tx.Exec(`UPDATE types SET name = ? WHERE id = ?`, name, id)
+		return err
+	})
 }
 
 func (a *App) DeleteType(id int64) error {
-	// Set type_id to NULL for all sets using this type
-	if _, err := a.db.Exec(`UPDATE sets SET type_id = NULL WHERE type_id = ?`, id); err != nil {
+	return a.updateLinkedSets(`SELECT id FROM sets WHERE type_id = ?`, id, func(tx *dbTx) error {
+		// Set type_id to NULL for all sets using this type
+		if _, err := tx.Exec(`UPDATE sets SET type_id = NULL WHERE type_id = ?`, id); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`DELETE FROM types WHERE id = ?`, id)
 		return err
-	}
-	_, err := a.db.Exec(`DELETE FROM types WHERE id = ?`, id)
-	return err
+	})
 }
 
-// GetNextBagSerial returns the next available bag serial number for a given box
-func (a *App) GetNextBagSerial(boxID int64) (string, error) {
-	if boxID <= 0 {
-		return "0001", nil
-	}
-
-	var maxSerial sql.NullString
-	err := a.db.QueryRow(`
-		SELECT MAX(serial_no) 
-		FROM bags 
-		WHERE box_id = ? AND serial_no GLOB '[0-9]*'
-	`, boxID).Scan(&maxSerial)
-
-	if err != nil || !maxSerial.Valid || maxSerial.String == "" {
-		return "0001", nil
+// updateLinkedSets runs change in a transaction and reports the sets listed
+// by setsQuery for id as updated. The sets are listed before change runs, so
+// links it removes are still reported.
+func (a *App) updateLinkedSets(setsQuery string, id int64, change func(tx *dbTx) error) error {
+	tx, err := a.db.Begin()
+	if err != nil {
+		return err
 	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
 
-	// Try to parse as number and increment
-	var num int
-	_, err = fmt.Sscanf(maxSerial.String, "%d", &num)
+	setIDs, err := listIDsTx(tx, setsQuery, id)
 	if err != nil {
-		return "0001", nil
+		return err
+	}
+	if err = change(tx); err != nil {
+		return err
 	}
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+	a.emitChange(EventSetUpdated, setIDs...)
+	return nil
+}
 
-	return fmt.Sprintf("%04d", num+1), nil
+// GetNextBagSerial returns the next available bag serial number for a given box
+func (a *App) GetNextBagSerial(boxID int64) (string, error) {
+	return nextBagSerial(a.db, boxID)
+}
+
+// queryer is satisfied by both *dbHandle and *dbTx.
+type queryer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*dbRows, error)
+	QueryRow(query string, args ...any) *dbRow
 }
 
 // Bags & Sets
@@ -295,9 +364,6 @@ func (a *App) CreateBagWithSet(boxID int64, serialNo, setName, manufacturerName,
 		return 0, errors.New("box is required")
 	}
 	serialNo = normalizeName(serialNo)
-	if serialNo == "" {
-		return 0, errors.New("bag serial is required")
-	}
 
 	tx, err := a.db.Begin()
 	if err != nil {
@@ -309,6 +375,22 @@ func (a *App) CreateBagWithSet(boxID int64, serialNo, setName, manufacturerName,
 		}
 	}()
 
+	// An empty serial is allocated here so no other bag can take it first.
+	if serialNo == "" {
+		if serialNo, err = nextBagSerial(tx, boxID); err != nil {
+			return 0, err
+		}
+	} else {
+		var taken bool
+		if err = tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM bags WHERE box_id = ? AND serial_no = ?)`, boxID, serialNo).Scan(&taken); err != nil {
+			return 0, err
+		}
+		if taken {
+			err = fmt.Errorf("bag %s already exists in this box", serialNo)
+			return 0, err
+		}
+	}
+
 	res, err := tx.Exec(`INSERT INTO bags(box_id, serial_no) VALUES (?, ?)`, boxID, serialNo)
 	if err != nil {
 		return 0, err
@@ -320,16 +402,16 @@ func (a *App) CreateBagWithSet(boxID int64, serialNo, setName, manufacturerName,
 
 	var manufacturerID sql.NullInt64
 	if manufacturerName != "" {
-		id, err := ensureManufacturerTx(tx, manufacturerName)
-		if err != nil {
+		var id int64
+		if id, err = ensureManufacturerTx(tx, manufacturerName); err != nil {
 			return 0, err
 		}
 		manufacturerID = sql.NullInt64{Int64: id, Valid: true}
 	}
 	var typeID sql.NullInt64
 	if typeName != "" {
-		id, err := ensureTypeTx(tx, typeName)
-		if err != nil {
+		var id int64
+		if id, err = ensureTypeTx(tx, typeName); err != nil {
 			return 0, err
 		}
 		typeID = sql.NullInt64{Int64: id, Valid: true}
@@ -344,11 +426,14 @@ func (a *App) CreateBagWithSet(boxID int64, serialNo, setName, manufacturerName,
 		return 0, err
 	}
 
-	err = tx.Commit()
-	return setID, err
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+	a.emitChange(EventSetCreated, setID)
+	return setID, nil
 }
 
-func ensureManufacturerTx(tx *sql.Tx, name string) (int64, error) {
+func ensureManufacturerTx(tx *dbTx, name string) (int64, error) {
 	name = normalizeName(name)
 	if name == "" {
 		return 0, nil
@@ -370,7 +455,7 @@ func ensureManufacturerTx(tx *sql.Tx, name string) (int64, error) {
 	return res.LastInsertId()
 }
 
-func ensureTypeTx(tx *sql.Tx, name string) (int64, error) {
+func ensureTypeTx(tx *dbTx, name string) (int64, error) {
 	name = normalizeName(name)
 	if name == "" {
 		return 0, nil
@@ -421,8 +506,8 @@ func (a *App) UpdateSet(setID int64, setName, manufacturerName, typeName string,
 
 	var manufacturerID sql.NullInt64
 	if manufacturerName != "" {
-		mID, err := ensureManufacturerTx(tx, manufacturerName)
-		if err != nil {
+		var mID int64
+		if mID, err = ensureManufacturerTx(tx, manufacturerName); err != nil {
 			return err
 		}
 		manufacturerID = sql.NullInt64{Int64: mID, Valid: true}
@@ -430,8 +515,8 @@ func (a *App) UpdateSet(setID int64, setName, manufacturerName, typeName string,
 
 	var typeID sql.NullInt64
 	if typeName != "" {
-		tID, err := ensureTypeTx(tx, typeName)
-		if err != nil {
+		var tID int64
+		if tID, err = ensureTypeTx(tx, typeName); err != nil {
 			return err
 		}
 		typeID = sql.NullInt64{Int64: tID, Valid: true}
@@ -444,52 +529,88 @@ func (a *App) UpdateSet(setID int64, setName, manufacturerName, typeName string,
 		return err
 	}
 
-	err = tx.Commit()
-	return err
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+	a.emitChange(EventSetUpdated, setID)
+	return nil
 }
 
+// DeleteSet moves a set to the trash. Its bag, tags, products and image are
+// kept until the set is purged.
 func (a *App) DeleteSet(setID int64) error {
-	tx, err := a.db.Begin()
+	release, err := a.db.hold()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	act, err := a.trashSet(setID)
 	if err != nil {
 		return err
 	}
+	a.pushUndo(act)
+	a.emitChange(EventSetDeleted, setID)
+	return nil
+}
+
+// purgeSet removes a set with its bag and moves the photo into the undo
+// holding area instead of deleting it.
+func (a *App) purgeSet(setID int64) (*undoAction, error) {
+	tx, err := a.db.Begin()
+	if err != nil {
+		return nil, err
+	}
 	defer func() {
 		if err != nil {
 			_ = tx.Rollback()
 		}
 	}()
 
-	var photoPath sql.NullString
+	var name string
+	var photoPath, originalPath sql.NullString
 	var bagID sql.NullInt64
-	if err = tx.QueryRow(`SELECT photo_path, bag_id FROM sets WHERE id = ?`, setID).Scan(&photoPath, &bagID); err != nil {
-		return err
+	if err = tx.QueryRow(`SELECT name, photo_path, original_path, bag_id FROM sets WHERE id = ?`, setID).Scan(&name, &photoPath, &originalPath, &bagID); err != nil {
+		return nil, err
+	}
+
+	snaps, err := snapshotBagTree(tx, `id = ?`, bagID.Int64)
+	if err != nil {
+		return nil, err
 	}
 
 	if _, err = tx.Exec(`DELETE FROM sets WHERE id = ?`, setID); err != nil {
-		return err
+		return nil, err
 	}
 
 	if bagID.Valid {
 		if _, err = tx.Exec(`DELETE FROM bags WHERE id = ?`, bagID.Int64); err != nil {
-			return err
+			return nil, err
 		}
 	}
 
 	if err = tx.Commit(); err != nil {
-		return err
+		return nil, err
 	}
 
-	if photoPath.Valid && photoPath.String != "" {
-		_ = deleteLocalImage(a.paths.ImagesDir, photoPath.String)
+	act := &undoAction{
+		description: fmt.Sprintf("Permanently delete set %q", name),
+		snapshots:   snaps,
+		redo:        func() (*undoAction, error) { return a.purgeSet(setID) },
 	}
-	return nil
+	for _, p := range []sql.NullString{photoPath, originalPath} {
+		if p.Valid {
+			a.holdImages(act, []string{p.String})
+		}
+	}
+	return act, nil
 }
 
 func (a *App) GetSet(setID int64) (SetDetails, error) {
 	var details SetDetails
 	row := a.db.QueryRow(`
-		SELECT s.id, s.name, s.manufacturer_id, IFNULL(m.name,''), s.type_id, IFNULL(tp.name,''), IFNULL(s.photo_path,''), IFNULL(s.photo_source,''),
-		       b.id, b.serial_no, bx.id, bx.code, IFNULL(bx.name,''), loc.id, IFNULL(loc.friendly_name,''), IFNULL(loc.note,''),
+		SELECT s.id, s.name, s.manufacturer_id, IFNULL(m.name,''), s.type_id, IFNULL(tp.name,''), IFNULL(s.photo_path,''), IFNULL(s.original_path,''), IFNULL(s.photo_source,''),
+		       IFNULL(s.deleted_at,''), IFNULL(s.barcode,''), IFNULL(s.catalog_no,''), b.id, b.serial_no, bx.id, bx.code, IFNULL(bx.name,''), loc.id, IFNULL(loc.friendly_name,''), IFNULL(loc.note,''),
 		       IFNULL(loc.room,''), IFNULL(loc.shelf,''), IFNULL(loc.compartment,'')
 		FROM sets s
 		JOIN bags b ON b.id = s.bag_id
@@ -504,7 +625,7 @@ func (a *App) GetSet(setID int64) (SetDetails, error) {
 	var bag BagInfo
 	if err := row.Scan(
 		&details.ID, &details.Name, &manufacturerID, &details.ManufacturerName, &typeID, &details.TypeName,
-		&details.PhotoPath, &details.PhotoSource,
+		&details.PhotoPath, &details.OriginalPath, &details.PhotoSource, &details.DeletedAt, &details.Barcode, &details.CatalogNo,
 		&bag.ID, &bag.SerialNo, &bag.BoxID, &bag.BoxCode, &bag.BoxName, &bag.LocationID, &bag.LocationName, &bag.LocationNote,
 		&bag.LocationRoom, &bag.LocationShelf, &bag.LocationCompartment,
 	); err != nil {
@@ -535,14 +656,14 @@ func (a *App) GetSet(setID int64) (SetDetails, error) {
 		return details, err
 	}
 
-	eRows, err := a.db.Query(`SELECT id, set_id, name, IFNULL(kind,'') FROM elements WHERE set_id = ? ORDER BY id`, setID)
+	eRows, err := a.db.Query(`SELECT id, set_id, name, IFNULL(kind,''), IFNULL(barcode,'') FROM elements WHERE set_id = ? ORDER BY id`, setID)
 	if err != nil {
 		return details, err
 	}
 	defer eRows.Close()
 	for eRows.Next() {
 		var e Product
-		if err := eRows.Scan(&e.ID, &e.SetID, &e.Name, &e.Kind); err != nil {
+		if err := eRows.Scan(&e.ID, &e.SetID, &e.Name, &e.Kind, &e.Barcode); err != nil {
 			return details, err
 		}
 		details.Products = append(details.Products, e)
@@ -552,7 +673,7 @@ func (a *App) GetSet(setID int64) (SetDetails, error) {
 
 // Produkte
 func (a *App) ListProductsBySet(setID int64) ([]Product, error) {
-	rows, err := a.db.Query(`SELECT id, set_id, name, IFNULL(kind,'') FROM elements WHERE set_id = ? ORDER BY id`, setID)
+	rows, err := a.db.Query(`SELECT id, set_id, name, IFNULL(kind,''), IFNULL(barcode,'') FROM elements WHERE set_id = ? ORDER BY id`, setID)
 	if err != nil {
 		return nil, err
 	}
@@ -560,7 +681,7 @@ func (a *App) ListProductsBySet(setID int64) ([]Product, error) {
 	var elems []Product
 	for rows.Next() {
 		var e Product
-		if err := rows.Scan(&e.ID, &e.SetID, &e.Name, &e.Kind); err != nil {
+		if err := rows.Scan(&e.ID, &e.SetID, &e.Name, &e.Kind, &e.Barcode); err != nil {
 			return nil, err
 		}
 		elems = append(elems, e)
@@ -584,7 +705,12 @@ func (a *App) AddProduct(setID int64, name, kind string) (int64, error) {
 	if err != nil {
 		return 0, err
 	}
-	return res.LastInsertId()
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	a.emitChange(EventSetUpdated, setID)
+	return id, nil
 }
 
 func (a *App) UpdateProduct(id int64, name, kind string) error {
@@ -597,12 +723,74 @@ func (a *App) UpdateProduct(id int64, name, kind string) error {
 		return errors.New("invalid produkt kind")
 	}
 	_, err := a.db.Exec(`UPDATE elements SET name = ?, kind = NULLIF(?, '') WHERE id = ?`, name, kind, id)
-	return err
+	if err != nil {
+		return err
+	}
+	a.emitProductChange(id)
+	return nil
 }
 
 func (a *App) DeleteProduct(id int64) error {
-	_, err := a.db.Exec(`DELETE FROM elements WHERE id = ?`, id)
-	return err
+	release, err := a.db.hold()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	act, err := a.deleteProduct(id)
+	if err != nil {
+		return err
+	}
+	a.pushUndo(act)
+	a.emitChanges(act.changes)
+	return nil
+}
+
+// emitProductChange reports the set owning a product as updated.
+func (a *App) emitProductChange(productID int64) {
+	var setID int64
+	if err := a.db.QueryRow(`SELECT set_id FROM elements WHERE id = ?`, productID).Scan(&setID); err == nil {
+		a.emitChange(EventSetUpdated, setID)
+	}
+}
+
+func (a *App) deleteProduct(id int64) (*undoAction, error) {
+	tx, err := a.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var name string
+	var setID int64
+	err = tx.QueryRow(`SELECT name, set_id FROM elements WHERE id = ?`, id).Scan(&name, &setID)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = errors.New("product not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	snap, err := snapshotRows(tx, "elements", `id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = tx.Exec(`DELETE FROM elements WHERE id = ?`, id); err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &undoAction{
+		description: fmt.Sprintf("Delete product %q", name),
+		snapshots:   []tableSnapshot{snap},
+		redo:        func() (*undoAction, error) { return a.deleteProduct(id) },
+		changes:     []pendingChange{{EventSetUpdated, []int64{setID}}},
+	}, nil
 }
 
 // Tags
@@ -646,19 +834,22 @@ func (a *App) SetTags(setID int64, tagNames []string) error {
 		if tag == "" {
 			continue
 		}
-		tagID, err := ensureTagTx(tx, tag)
-		if err != nil {
+		var tagID int64
+		if tagID, err = ensureTagTx(tx, tag); err != nil {
 			return err
 		}
 		if _, err = tx.Exec(`INSERT OR IGNORE INTO set_tags(set_id, tag_id) VALUES (?, ?)`, setID, tagID); err != nil {
 			return err
 		}
 	}
-	err = tx.Commit()
-	return err
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+	a.emitChange(EventSetUpdated, setID)
+	return nil
 }
 
-func ensureTagTx(tx *sql.Tx, name string) (int64, error) {
+func ensureTagTx(tx *dbTx, name string) (int64, error) {
 	var id int64
 	err := tx.QueryRow(`SELECT id FROM tags WHERE LOWER(name) = ?`, normalizeLower(name)).Scan(&id)
 	if err == nil {
@@ -731,17 +922,74 @@ func (a *App) UpdateTag(id int64, name string) error {
 	if name == "" {
 		return errors.New("name is required")
 	}
-	_, err := a.db.Exec(`UPDATE tags SET name = ? WHERE id = ?`, name, id)
-	return err
+	return a.updateLinkedSets(`SELECT set_id FROM set_tags WHERE tag_id = ?`, id, func(tx *dbTx) error {
+		_, err := tx.Exec(`UPDATE tags SET name = ? WHERE id = ?`, name, id)
+		return err
+	})
 }
 
 func (a *App) DeleteTag(id int64) error {
-	// Remove all set_tags associations first
-	if _, err := a.db.Exec(`DELETE FROM set_tags WHERE tag_id = ?`, id); err != nil {
+	release, err := a.db.hold()
+	if err != nil {
 		return err
 	}
-	_, err := a.db.Exec(`DELETE FROM tags WHERE id = ?`, id)
-	return err
+	defer release()
+
+	act, err := a.deleteTag(id)
+	if err != nil {
+		return err
+	}
+	a.pushUndo(act)
+	a.emitChanges(act.changes)
+	return nil
+}
+
+func (a *App) deleteTag(id int64) (*undoAction, error) {
+	tx, err := a.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var name string
+	if err = tx.QueryRow(`SELECT name FROM tags WHERE id = ?`, id).Scan(&name); err != nil {
+		return nil, err
+	}
+	tag, err := snapshotRows(tx, "tags", `id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	links, err := snapshotRows(tx, "set_tags", `tag_id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+
+	setIDs, err := listIDsTx(tx, `SELECT set_id FROM set_tags WHERE tag_id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Remove all set_tags associations first
+	if _, err = tx.Exec(`DELETE FROM set_tags WHERE tag_id = ?`, id); err != nil {
+		return nil, err
+	}
+	if _, err = tx.Exec(`DELETE FROM tags WHERE id = ?`, id); err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &undoAction{
+		description: fmt.Sprintf("Delete tag %q", name),
+		snapshots:   []tableSnapshot{tag, links},
+		redo:        func() (*undoAction, error) { return a.deleteTag(id) },
+		changes:     []pendingChange{{EventSetUpdated, setIDs}},
+	}, nil
 }
 
 // Fuzzy search helper - checks if query parts match target with fuzzy matching
@@ -807,8 +1055,21 @@ func parseSearchQuery(query string) (searchTerm string, filters map[string]strin
 // Search with sorting options and special filters
 // sortBy: "name" (default), "box", "location", "added"
 // Supports @Box, @Produkt, @Hersteller, @Tag, @Ort prefixes
+// A bare 8, 12 or 13 digit query is matched against set and product barcodes.
 func (a *App) SearchSets(query string, sortBy string) ([]SetSearchResult, error) {
 	searchTerm, filters := parseSearchQuery(query)
+	if len(filters) == 0 && isBarcodeQuery(searchTerm) {
+		filters["barcode"] = normalizeBarcode(searchTerm)
+		searchTerm = ""
+	}
+
+	if sortBy == "" {
+		general, err := readGeneralSettings(a.db)
+		if err != nil {
+			return nil, err
+		}
+		sortBy = general.DefaultSort
+	}
 
 	// Determine ORDER BY clause
 	orderClause := "s.name"
@@ -823,7 +1084,7 @@ func (a *App) SearchSets(query string, sortBy string) ([]SetSearchResult, error)
 		orderClause = "s.name"
 	}
 
-	var rows *sql.Rows
+	var rows *dbRows
 	var err error
 
 	baseQuery := `
@@ -848,38 +1109,41 @@ func (a *App) SearchSets(query string, sortBy string) ([]SetSearchResult, error)
 
 		if boxFilter, ok := filters["box"]; ok {
 			like := "%" + strings.ToLower(boxFilter) + "%"
-			whereClause = `WHERE LOWER(bx.code) LIKE ? OR LOWER(bx.name) LIKE ?`
+			whereClause = `WHERE s.deleted_at IS NULL AND (LOWER(bx.code) LIKE ? OR LOWER(bx.name) LIKE ?)`
 			args = []interface{}{like, like}
 		} else if productFilter, ok := filters["product"]; ok {
 			like := "%" + strings.ToLower(productFilter) + "%"
-			whereClause = `LEFT JOIN elements e ON e.set_id = s.id WHERE LOWER(e.name) LIKE ?`
+			whereClause = `LEFT JOIN elements e ON e.set_id = s.id WHERE s.deleted_at IS NULL AND LOWER(e.name) LIKE ?`
 			args = []interface{}{like}
 		} else if manuFilter, ok := filters["manufacturer"]; ok {
 			like := "%" + strings.ToLower(manuFilter) + "%"
-			whereClause = `WHERE LOWER(m.name) LIKE ?`
+			whereClause = `WHERE s.deleted_at IS NULL AND LOWER(m.name) LIKE ?`
 			args = []interface{}{like}
 		} else if tagFilter, ok := filters["tag"]; ok {
 			like := "%" + strings.ToLower(tagFilter) + "%"
-			whereClause = `WHERE LOWER(t.name) LIKE ?`
+			whereClause = `WHERE s.deleted_at IS NULL AND LOWER(t.name) LIKE ?`
 			args = []interface{}{like}
 		} else if locFilter, ok := filters["location"]; ok {
 			like := "%" + strings.ToLower(locFilter) + "%"
-			whereClause = `WHERE LOWER(loc.friendly_name) LIKE ? OR LOWER(loc.room) LIKE ?`
+			whereClause = `WHERE s.deleted_at IS NULL AND (LOWER(loc.friendly_name) LIKE ? OR LOWER(loc.room) LIKE ?)`
 			args = []interface{}{like, like}
+		} else if code, ok := filters["barcode"]; ok {
+			whereClause = `LEFT JOIN elements e ON e.set_id = s.id WHERE s.deleted_at IS NULL AND (s.barcode = ? OR e.barcode = ?)`
+			args = []interface{}{code, code}
 		}
 
 		rows, err = a.db.Query(fmt.Sprintf(baseQuery, whereClause, orderClause), args...)
 	} else if searchTerm == "" {
 		// No search - show all
-		rows, err = a.db.Query(fmt.Sprintf(baseQuery, "", orderClause))
+		rows, err = a.db.Query(fmt.Sprintf(baseQuery, "WHERE s.deleted_at IS NULL", orderClause))
 	} else {
 		// General search across all fields
 		like := "%" + strings.ToLower(searchTerm) + "%"
 		whereClause := `
 			LEFT JOIN elements e ON e.set_id = s.id
-			WHERE LOWER(s.name) LIKE ? OR LOWER(t.name) LIKE ? OR LOWER(e.name) LIKE ? 
+			WHERE s.deleted_at IS NULL AND (LOWER(s.name) LIKE ? OR LOWER(t.name) LIKE ? OR LOWER(e.name) LIKE ? 
 			      OR LOWER(bx.code) LIKE ? OR LOWER(bx.name) LIKE ? OR LOWER(b.serial_no) LIKE ?
-			      OR LOWER(loc.friendly_name) LIKE ? OR LOWER(m.name) LIKE ?`
+			      OR LOWER(loc.friendly_name) LIKE ? OR LOWER(m.name) LIKE ?)`
 		rows, err = a.db.Query(fmt.Sprintf(baseQuery, whereClause, orderClause),
 			like, like, like, like, like, like, like, like)
 	}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+const (
+	// settingsVersion is the layout of the values in the settings table.
+	// Raise it together with a new entry in settingsUpgrades.
+	settingsVersion    = 1
+	settingsVersionKey = "settings.version"
+
+	settingGeneralLanguage      = "general.language"
+	settingGeneralDefaultSort   = "general.default_sort"
+	settingGeneralThumbnailSize = "general.thumbnail_size"
+
+	defaultThumbnailSize = 60
+
+	// EventSettingsChanged is emitted with the new Settings after every change.
+	EventSettingsChanged = "settings:changed"
+)
+
+// Settings holds every preference of a collection, grouped by feature.
+type Settings struct {
+	Version  int              `json:"version"`
+	General  GeneralSettings  `json:"general"`
+	Trash    TrashSettings    `json:"trash"`
+	Serial   SerialSettings   `json:"serial"`
+	Image    ImageSettings    `json:"image"`
+	Download DownloadSettings `json:"download"`
+	Scan     ScanSettings     `json:"scan"`
+	Backup   BackupSettings   `json:"backup"`
+}
+
+// GeneralSettings are interface preferences. Language is "de" or "en", or
+// empty to follow the interface; DefaultSort is the order SearchSets uses
+// when none is given; ThumbnailSize is the edge of the photos in the result
+// list in pixels.
+type GeneralSettings struct {
+	Language      string `json:"language"`
+	DefaultSort   string `json:"defaultSort"`
+	ThumbnailSize int    `json:"thumbnailSize"`
+}
+
+// settingsUpgrades rewrites stored values from one settings version to the
+// next; entry i upgrades version i to i+1.
+var settingsUpgrades = []func(q queryer) error{
+	// 0 -> 1: the values written before versioning already match version 1.
+	func(q queryer) error { return nil },
+}
+
+// readSetting reads a raw value from the settings table, returning def when unset.
+func readSetting(q queryer, key, def string) (string, error) {
+	var val string
+	err := q.QueryRow(`SELECT value FROM settings WHERE key = ?`, key).Scan(&val)
+	if errors.Is(err, sql.ErrNoRows) {
+		return def, nil
+	}
+	if err != nil {
+		return def, err
+	}
+	return val, nil
+}
+
+func writeSetting(q queryer, key, val string) error {
+	_, err := q.Exec(`INSERT INTO settings(key, value) VALUES (?, ?)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value`, key, val)
+	return err
+}
+
+func readGeneralSettings(q queryer) (GeneralSettings, error) {
+	defaults := GeneralSettings{DefaultSort: "name", ThumbnailSize: defaultThumbnailSize}
+	settings := defaults
+	lang, err := readSetting(q, settingGeneralLanguage, "")
+	if err != nil {
+		return settings, err
+	}
+	sort, err := readSetting(q, settingGeneralDefaultSort, "name")
+	if err != nil {
+		return settings, err
+	}
+	size, err := readSetting(q, settingGeneralThumbnailSize, strconv.Itoa(defaultThumbnailSize))
+	if err != nil {
+		return settings, err
+	}
+	settings.Language, settings.DefaultSort = lang, sort
+	if n, err := strconv.Atoi(size); err == nil {
+		settings.ThumbnailSize = n
+	}
+	if err := validateGeneralSettings(&settings); err != nil {
+		return defaults, nil
+	}
+	return settings, nil
+}
+
+func validateGeneralSettings(settings *GeneralSettings) error {
+	switch settings.Language {
+	case "", "de", "en":
+	default:
+		return fmt.Errorf("unknown language %q", settings.Language)
+	}
+	switch settings.DefaultSort {
+	case "":
+		settings.DefaultSort = "name"
+	case "name", "box", "location", "added":
+	default:
+		return fmt.Errorf("unknown sort order %q", settings.DefaultSort)
+	}
+	if settings.ThumbnailSize == 0 {
+		settings.ThumbnailSize = defaultThumbnailSize
+	}
+	if settings.ThumbnailSize < 40 || settings.ThumbnailSize > 200 {
+		return errors.New("thumbnail size must be between 40 and 200 pixels")
+	}
+	return nil
+}
+
+func writeGeneralSettings(q queryer, settings GeneralSettings) error {
+	if err := writeSetting(q, settingGeneralLanguage, settings.Language); err != nil {
+		return err
+	}
+	if err := writeSetting(q, settingGeneralDefaultSort, settings.DefaultSort); err != nil {
+		return err
+	}
+	return writeSetting(q, settingGeneralThumbnailSize, strconv.Itoa(settings.ThumbnailSize))
+}
+
+// readSettings collects every preference. Unset values take their defaults,
+// and a group holding an invalid value, for example one edited by hand or
+// written by another version, is read as its defaults.
+func readSettings(q queryer) (Settings, error) {
+	s := Settings{Version: settingsVersion}
+	var err error
+	if s.General, err = readGeneralSettings(q); err != nil {
+		return s, err
+	}
+	if s.Trash, err = readTrashSettings(q); err != nil {
+		return s, err
+	}
+	if s.Serial, err = readSerialSettings(q); err != nil {
+		return s, err
+	}
+	if s.Image, err = readImageSettings(q); err != nil {
+		return s, err
+	}
+	if s.Download, err = readDownloadSettings(q); err != nil {
+		return s, err
+	}
+	if s.Scan, err = readScanSettings(q); err != nil {
+		return s, err
+	}
+	if s.Backup, err = readBackupSettings(q); err != nil {
+		return s, err
+	}
+	return s, nil
+}
+
+// validateSettings checks every group and fills in defaults for empty values.
+func validateSettings(s *Settings) error {
+	if err := validateGeneralSettings(&s.General); err != nil {
+		return err
+	}
+	if err := validateTrashSettings(s.Trash); err != nil {
+		return err
+	}
+	if err := validateSerialSettings(&s.Serial); err != nil {
+		return err
+	}
+	if err := validateImageSettings(s.Image); err != nil {
+		return err
+	}
+	if err := validateDownloadSettings(s.Download); err != nil {
+		return err
+	}
+	if err := validateScanSettings(&s.Scan); err != nil {
+		return err
+	}
+	return validateBackupSettings(&s.Backup)
+}
+
+func writeSettings(q queryer, s Settings) error {
+	if err := writeGeneralSettings(q, s.General); err != nil {
+		return err
+	}
+	if err := writeTrashSettings(q, s.Trash); err != nil {
+		return err
+	}
+	if err := writeSerialSettings(q, s.Serial); err != nil {
+		return err
+	}
+	if err := writeImageSettings(q, s.Image); err != nil {
+		return err
+	}
+	if err := writeDownloadSettings(q, s.Download); err != nil {
+		return err
+	}
+	if err := writeScanSettings(q, s.Scan); err != nil {
+		return err
+	}
+	return writeBackupSettings(q, s.Backup)
+}
+
+// GetSettings returns all preferences of the open collection.
+func (a *App) GetSettings() (Settings, error) {
+	return readSettings(a.db)
+}
+
+// UpdateSettings validates and stores all preferences at once. It returns
+// the settings as stored, with defaults filled in.
+func (a *App) UpdateSettings(settings Settings) (Settings, error) {
+	return a.updateSettings(func(s *Settings) {
+		*s = settings
+	})
+}
+
+// updateSettings applies change to the current settings and stores the
+// result in one transaction, so a rejected value leaves everything as it was.
+func (a *App) updateSettings(change func(s *Settings)) (Settings, error) {
+	tx, err := a.db.Begin()
+	if err != nil {
+		return Settings{}, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	s, err := readSettings(tx)
+	if err != nil {
+		return Settings{}, err
+	}
+	change(&s)
+	s.Version = settingsVersion
+	if err = validateSettings(&s); err != nil {
+		return Settings{}, err
+	}
+	if err = writeSettings(tx, s); err != nil {
+		return Settings{}, err
+	}
+	if err = tx.Commit(); err != nil {
+		return Settings{}, err
+	}
+	a.emitEvent(EventSettingsChanged, s)
+	return s, nil
+}
+
+// upgradeSettings brings stored values written by an older version up to
+// settingsVersion. Settings from a newer version are left untouched.
+func (a *App) upgradeSettings() error {
+	tx, err := a.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	raw, err := readSetting(tx, settingsVersionKey, "0")
+	if err != nil {
+		return err
+	}
+	version, convErr := strconv.Atoi(raw)
+	if convErr != nil {
+		err = fmt.Errorf("invalid settings version %q", raw)
+		return err
+	}
+	if version >= settingsVersion {
+		return tx.Rollback()
+	}
+	for v := version; v < settingsVersion; v++ {
+		if err = settingsUpgrades[v](tx); err != nil {
+			return fmt.Errorf("upgrade settings to version %d: %w", v+1, err)
+		}
+	}
+	if err = writeSetting(tx, settingsVersionKey, strconv.Itoa(settingsVersion)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+const (
+	settingTrashRetentionDays = "trash.retention_days"
+	defaultTrashRetentionDays = 30
+)
+
+// TrashedSet is a deleted set waiting in the trash.
+type TrashedSet struct {
+	SetID            int64  `json:"setId"`
+	SetName          string `json:"setName"`
+	ManufacturerName string `json:"manufacturerName"`
+	BoxCode          string `json:"boxCode"`
+	BagSerial        string `json:"bagSerial"`
+	ThumbnailPath    string `json:"thumbnailPath"`
+	DeletedAt        string `json:"deletedAt"`
+}
+
+// TrashSettings controls how long deleted sets stay in the trash. Zero keeps them forever.
+type TrashSettings struct {
+	RetentionDays int `json:"retentionDays"`
+}
+
+// trashSet flags a set as deleted without touching its bag, tags, products or image.
+func (a *App) trashSet(setID int64) (*undoAction, error) {
+	var name string
+	if err := a.db.QueryRow(`SELECT name FROM sets WHERE id = ? AND deleted_at IS NULL`, setID).Scan(&name); err != nil {
+		return nil, err
+	}
+	if _, err := a.db.Exec(`UPDATE sets SET deleted_at = CURRENT_TIMESTAMP WHERE id = ?`, setID); err != nil {
+		return nil, err
+	}
+
+	return &undoAction{
+		description: fmt.Sprintf("Delete set %q", name),
+		revert: func(tx *dbTx) error {
+			_, err := tx.Exec(`UPDATE sets SET deleted_at = NULL WHERE id = ?`, setID)
+			return err
+		},
+		redo: func() (*undoAction, error) { return a.trashSet(setID) },
+	}, nil
+}
+
+// ListTrash returns all deleted sets, most recently deleted first.
+func (a *App) ListTrash() ([]TrashedSet, error) {
+	rows, err := a.db.Query(`
+		SELECT s.id, s.name, IFNULL(m.name,''), bx.code, b.serial_no, IFNULL(s.photo_path,''), s.deleted_at
+		FROM sets s
+		JOIN bags b ON b.id = s.bag_id
+		JOIN boxes bx ON bx.id = b.box_id
+		LEFT JOIN manufacturers m ON m.id = s.manufacturer_id
+		WHERE s.deleted_at IS NOT NULL
+		ORDER BY s.deleted_at DESC, s.id DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []TrashedSet
+	for rows.Next() {
+		var t TrashedSet
+		if err := rows.Scan(&t.SetID, &t.SetName, &t.ManufacturerName, &t.BoxCode, &t.BagSerial, &t.ThumbnailPath, &t.DeletedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, t)
+	}
+	return list, rows.Err()
+}
+
+// RestoreSet takes a set out of the trash.
+func (a *App) RestoreSet(setID int64) error {
+	res, err := a.db.Exec(`UPDATE sets SET deleted_at = NULL WHERE id = ? AND deleted_at IS NOT NULL`, setID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return errors.New("set is not in the trash")
+	}
+	a.emitChange(EventSetRestored, setID)
+	return nil
+}
+
+// PurgeSet permanently deletes a set from the trash, including its bag and image.
+func (a *App) PurgeSet(setID int64) error {
+	release, err := a.db.hold()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	var trashed bool
+	if err := a.db.QueryRow(`SELECT deleted_at IS NOT NULL FROM sets WHERE id = ?`, setID).Scan(&trashed); err != nil {
+		return err
+	}
+	if !trashed {
+		return errors.New("set is not in the trash")
+	}
+
+	act, err := a.purgeSet(setID)
+	if err != nil {
+		return err
+	}
+	a.pushUndo(act)
+	a.emitChange(EventSetPurged, setID)
+	return nil
+}
+
+// EmptyTrash permanently deletes every set in the trash and returns how many were removed.
+func (a *App) EmptyTrash() (int, error) {
+	return a.purgeTrashedSets(`deleted_at IS NOT NULL`)
+}
+
+func readTrashSettings(q queryer) (TrashSettings, error) {
+	defaults := TrashSettings{RetentionDays: defaultTrashRetentionDays}
+	settings := defaults
+	days, err := readSetting(q, settingTrashRetentionDays, strconv.Itoa(defaultTrashRetentionDays))
+	if err != nil {
+		return settings, err
+	}
+	if n, err := strconv.Atoi(days); err == nil {
+		settings.RetentionDays = n
+	}
+	if err := validateTrashSettings(settings); err != nil {
+		return defaults, nil
+	}
+	return settings, nil
+}
+
+func validateTrashSettings(settings TrashSettings) error {
+	if settings.RetentionDays < 0 {
+		return errors.New("retention days cannot be negative")
+	}
+	return nil
+}
+
+func writeTrashSettings(q queryer, settings TrashSettings) error {
+	return writeSetting(q, settingTrashRetentionDays, strconv.Itoa(settings.RetentionDays))
+}
+
+func (a *App) GetTrashRetentionDays() (int, error) {
+	settings, err := readTrashSettings(a.db)
+	return settings.RetentionDays, err
+}
+
+// SetTrashRetentionDays sets how long deleted sets stay in the trash. Zero keeps them forever.
+func (a *App) SetTrashRetentionDays(days int) error {
+	_, err := a.updateSettings(func(s *Settings) {
+		s.Trash.RetentionDays = days
+	})
+	return err
+}
+
+// purgeExpiredTrash removes sets that have been in the trash longer than the retention period.
+func (a *App) purgeExpiredTrash() (int, error) {
+	days, err := a.GetTrashRetentionDays()
+	if err != nil || days <= 0 {
+		return 0, err
+	}
+	return a.purgeTrashedSets(`deleted_at IS NOT NULL AND deleted_at <= datetime('now', ?)`, fmt.Sprintf("-%d days", days))
+}
+
+// purgeTrashedSets hard-deletes the matching sets with their bags and image files.
+// These deletions are not recorded for undo.
+func (a *App) purgeTrashedSets(where string, args ...any) (int, error) {
+	tx, err := a.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	rows, err := tx.Query(`SELECT id, IFNULL(photo_path,''), IFNULL(original_path,'') FROM sets WHERE `+where, args...)
+	if err != nil {
+		return 0, err
+	}
+	var photos []string
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		var p, orig string
+		if err = rows.Scan(&id, &p, &orig); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+		for _, img := range []string{p, orig} {
+			if img != "" {
+				photos = append(photos, img)
+			}
+		}
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+
+	// Removing the bag cascades to the set, its products and tag links.
+	if _, err = tx.Exec(`DELETE FROM bags WHERE id IN (SELECT bag_id FROM sets WHERE `+where+`)`, args...); err != nil {
+		return 0, err
+	}
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	for _, p := range photos {
+		_ = a.deleteLocalImage(p)
+	}
+	a.emitChange(EventSetPurged, ids...)
+	return len(ids), nil
+}
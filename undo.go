@@ -0,0 +1,349 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// maxUndoDepth limits how many destructive operations can be reversed.
+const maxUndoDepth = 20
+
+// UndoState tells the UI what Undo and Redo would do next.
+type UndoState struct {
+	CanUndo         bool   `json:"canUndo"`
+	UndoDescription string `json:"undoDescription"`
+	CanRedo         bool   `json:"canRedo"`
+	RedoDescription string `json:"redoDescription"`
+}
+
+// tableSnapshot holds raw rows of a table so they can be written back verbatim.
+type tableSnapshot struct {
+	table   string
+	columns []string
+	rows    [][]any
+}
+
+// heldImage is an image file moved out of Images/ while its set can still be restored.
+type heldImage struct {
+	relPath  string
+	holdPath string
+}
+
+// undoAction captures everything needed to reverse one destructive operation.
+// Snapshots are ordered parent first so they can be re-inserted in sequence;
+// revert covers operations that only flag rows instead of removing them.
+// changes are the entity events the operation caused besides its own.
+type undoAction struct {
+	description string
+	snapshots   []tableSnapshot
+	revert      func(tx *dbTx) error
+	images      []heldImage
+	redo        func() (*undoAction, error)
+	changes     []pendingChange
+}
+
+type undoHistory struct {
+	mu   sync.Mutex
+	undo []*undoAction
+	redo []*undoAction
+}
+
+func snapshotRows(tx *dbTx, table, where string, args ...any) (tableSnapshot, error) {
+	snap := tableSnapshot{table: table}
+	rows, err := tx.Query(fmt.Sprintf(`SELECT * FROM %s WHERE %s`, table, where), args...)
+	if err != nil {
+		return snap, err
+	}
+	defer rows.Close()
+
+	snap.columns, err = rows.Columns()
+	if err != nil {
+		return snap, err
+	}
+	for rows.Next() {
+		values := make([]any, len(snap.columns))
+		ptrs := make([]any, len(values))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return snap, err
+		}
+		snap.rows = append(snap.rows, values)
+	}
+	return snap, rows.Err()
+}
+
+func (s tableSnapshot) restore(tx *dbTx) error {
+	if len(s.rows) == 0 {
+		return nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(s.columns)), ",")
+	stmt := fmt.Sprintf(`INSERT INTO %s(%s) VALUES (%s)`, s.table, strings.Join(s.columns, ", "), placeholders)
+	for _, row := range s.rows {
+		if _, err := tx.Exec(stmt, row...); err != nil {
+			return fmt.Errorf("restore %s: %w", s.table, err)
+		}
+	}
+	return nil
+}
+
+// snapshotBagTree captures every bag matching bagWhere together with its set,
+// products and tag links.
+func snapshotBagTree(tx *dbTx, bagWhere string, args ...any) ([]tableSnapshot, error) {
+	bagIDs := `SELECT id FROM bags WHERE ` + bagWhere
+	setIDs := `SELECT id FROM sets WHERE bag_id IN (` + bagIDs + `)`
+	queries := []struct {
+		table string
+		where string
+	}{
+		{"bags", bagWhere},
+		{"sets", `bag_id IN (` + bagIDs + `)`},
+		{"elements", `set_id IN (` + setIDs + `)`},
+		{"set_tags", `set_id IN (` + setIDs + `)`},
+	}
+	var snaps []tableSnapshot
+	for _, q := range queries {
+		snap, err := snapshotRows(tx, q.table, q.where, args...)
+		if err != nil {
+			return nil, err
+		}
+		snaps = append(snaps, snap)
+	}
+	return snaps, nil
+}
+
+// holdImage moves an image out of Images/ into the undo holding area.
+// Images still used by other sets stay in place and a copy is held instead,
+// since those sets may drop the image before the action is undone.
+// Paths outside the images directory are left untouched.
+func (a *App) holdImage(relPath string) (heldImage, bool) {
+	paths := a.paths()
+	if relPath == "" || filepath.IsAbs(relPath) {
+		return heldImage{}, false
+	}
+	src := filepath.Join(paths.BaseDir, relPath)
+	if !strings.HasPrefix(filepath.Clean(src), filepath.Clean(paths.ImagesDir)) {
+		return heldImage{}, false
+	}
+	refs, err := imageRefCount(a.db, relPath)
+	if err != nil {
+		return heldImage{}, false
+	}
+	dst := filepath.Join(paths.UndoDir, uuid.NewString()+"_"+filepath.Base(src))
+	if refs > 0 {
+		err = copyFile(src, dst)
+	} else {
+		err = os.Rename(src, dst)
+	}
+	if err != nil {
+		return heldImage{}, false
+	}
+	return heldImage{relPath: relPath, holdPath: dst}, true
+}
+
+func (a *App) holdImages(act *undoAction, relPaths []string) {
+	for _, p := range relPaths {
+		if img, ok := a.holdImage(p); ok {
+			act.images = append(act.images, img)
+		}
+	}
+}
+
+func (a *App) releaseImage(img heldImage) error {
+	dst := filepath.Join(a.paths().BaseDir, img.relPath)
+	if _, err := os.Stat(dst); err == nil {
+		// Content-addressed: the file in place is identical to the held one.
+		return os.Remove(img.holdPath)
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	return os.Rename(img.holdPath, dst)
+}
+
+// copyFile hard-links src to dst where possible and copies it otherwise.
+func copyFile(src, dst string) error {
+	if err := os.Link(src, dst); err == nil {
+		return nil
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// discard permanently drops the held images of an action that can no longer be undone.
+func (act *undoAction) discard() {
+	for _, img := range act.images {
+		_ = os.Remove(img.holdPath)
+	}
+}
+
+func (a *App) pushUndo(act *undoAction) {
+	h := &a.history
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, r := range h.redo {
+		r.discard()
+	}
+	h.redo = nil
+	h.undo = append(h.undo, act)
+	if len(h.undo) > maxUndoDepth {
+		h.undo[0].discard()
+		h.undo = h.undo[1:]
+	}
+}
+
+// clearUndoHistory forgets all recorded actions and empties the holding area in undoDir.
+func (a *App) clearUndoHistory(undoDir string) {
+	h := &a.history
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.undo = nil
+	h.redo = nil
+	h.removeUnheld(undoDir)
+}
+
+// removeUnheldImages empties the holding area of the open collection of files
+// no recorded action refers to, such as those left behind by a crash.
+func (a *App) removeUnheldImages() {
+	h := &a.history
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.removeUnheld(a.paths().UndoDir)
+}
+
+// removeUnheld deletes the files in undoDir that no action holds. The caller holds h.mu.
+func (h *undoHistory) removeUnheld(undoDir string) {
+	if undoDir == "" {
+		return
+	}
+	entries, err := os.ReadDir(undoDir)
+	if err != nil {
+		return
+	}
+	held := make(map[string]bool)
+	for _, acts := range [][]*undoAction{h.undo, h.redo} {
+		for _, act := range acts {
+			for _, img := range act.images {
+				held[img.holdPath] = true
+			}
+		}
+	}
+	for _, e := range entries {
+		if p := filepath.Join(undoDir, e.Name()); !held[p] {
+			_ = os.RemoveAll(p)
+		}
+	}
+}
+
+// GetUndoState describes the operations Undo and Redo would reverse or repeat.
+func (a *App) GetUndoState() UndoState {
+	h := &a.history
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var state UndoState
+	if n := len(h.undo); n > 0 {
+		state.CanUndo = true
+		state.UndoDescription = h.undo[n-1].description
+	}
+	if n := len(h.redo); n > 0 {
+		state.CanRedo = true
+		state.RedoDescription = h.redo[n-1].description
+	}
+	return state
+}
+
+// Undo reverses the most recent destructive operation and returns its description.
+func (a *App) Undo() (string, error) {
+	h := &a.history
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	n := len(h.undo)
+	if n == 0 {
+		return "", errors.New("nothing to undo")
+	}
+	act := h.undo[n-1]
+	if err := a.applyUndo(act); err != nil {
+		return "", fmt.Errorf("undo %q failed: %w", act.description, err)
+	}
+	h.undo = h.undo[:n-1]
+	h.redo = append(h.redo, act)
+	a.emitEvent(EventHistoryApplied, act.description)
+	return act.description, nil
+}
+
+// Redo repeats the most recently undone operation and returns its description.
+func (a *App) Redo() (string, error) {
+	h := &a.history
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	n := len(h.redo)
+	if n == 0 {
+		return "", errors.New("nothing to redo")
+	}
+	act := h.redo[n-1]
+	next, err := act.redo()
+	if err != nil {
+		return "", fmt.Errorf("redo %q failed: %w", act.description, err)
+	}
+	h.redo = h.redo[:n-1]
+	h.undo = append(h.undo, next)
+	a.emitEvent(EventHistoryApplied, act.description)
+	return act.description, nil
+}
+
+func (a *App) applyUndo(act *undoAction) error {
+	tx, err := a.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	for _, snap := range act.snapshots {
+		if err = snap.restore(tx); err != nil {
+			return err
+		}
+	}
+	if act.revert != nil {
+		if err = act.revert(tx); err != nil {
+			return err
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	for _, img := range act.images {
+		_ = a.releaseImage(img)
+	}
+	act.images = nil
+	return nil
+}